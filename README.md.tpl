@@ -19,12 +19,84 @@ This makes possible to measure coverage on ELF binaries without ecosystem-specif
 xcover run --pid PID
 ```
 
+### Filter by cgroup
+
+To trace only the processes in a cgroup v2 hierarchy, like the ones of a container, pass the cgroup path, relative to `/sys/fs/cgroup` or absolute, or its ID:
+
+```shell
+xcover run --path EXE_PATH --cgroup /system.slice/docker-CONTAINER_ID.scope
+```
+
 ### Filter by binary
 
 ```shell
 xcover run --path EXE_PATH
 ```
 
+### Multiple executables
+
+To trace cooperating programs together, like an API server, a worker and a CLI, repeat the `--path` flag:
+
+```shell
+xcover run --path /path/to/api --path /path/to/worker --path /path/to/cli
+```
+
+A single profiler traces all of them, and the report is their aggregate, whose functions are qualified by the name of their executable (e.g. `api:main.main`), with the report of each executable in `executables`.
+The function filters and the shared libraries apply to each executable. When launching the program (see [Filter by process tree](#filter-by-process-tree)), the first executable is started. The process cannot be filtered with `--pid`, as it runs a single executable.
+
+### Filter by process tree
+
+xcover can start the program itself with the arguments passed after `--`, and trace only the functions called by that process and its descendants:
+
+```shell
+xcover run --path EXE_PATH -- ARG...
+```
+
+The probes are attached before the program executes its first instruction, so the functions called at initialization are traced too.
+When the program exits, the report is written and xcover exits with the exit code of the program, or with code `2` when the coverage does not meet the [thresholds](#coverage-thresholds).
+
+### Stripped binaries
+
+For stripped binaries, the function symbols are read from the separate debug info file, looked up by:
+* build ID, in `/usr/lib/debug/.build-id/xx/yyyy.debug`
+* debug link (`.gnu_debuglink`), in the directory of the binary, in its `.debug` subdirectory and in `/usr/lib/debug`
+
+while uprobes are still attached to the stripped binary.
+
+The debug info file can also be specified explicitly:
+
+```shell
+xcover run --path EXE_PATH --debug-file EXE_PATH.debug
+```
+
+Go binaries stripped of the symbol table (e.g. built with `-ldflags="-s -w"`) and without a separate debug info file are supported too, as the function symbols are recovered from the Go pclntab.
+
+### Shared libraries
+
+For tracing also the functions of shared libraries, specify them:
+
+```shell
+xcover run --path EXE_PATH --lib /usr/lib/libfoo.so --lib /usr/lib/libbar.so
+```
+
+or trace the shared libraries needed by the executable (`DT_NEEDED`):
+
+```shell
+xcover run --path EXE_PATH --needed-libs --include "^foo_"
+```
+
+The function filters apply to the shared libraries too. For stripped shared libraries, the functions are read from the dynamic symbol table when no separate debug info file is found.
+
+The report then includes the coverage of each ELF object in `objects`.
+
+For tracing also the shared libraries loaded at runtime, like plugins loaded with `dlopen`, specify the regex pattern of their paths:
+
+```shell
+xcover run --path EXE_PATH --follow-libs "/plugins/.*\.so$"
+```
+
+The memory mappings of the processes of the executable (or of the process filtered with `--pid`) are periodically scanned (see `--follow-libs-interval`), and the functions of the new shared libraries matching the pattern are traced as soon as they are found.
+
 ### Filter functions
 
 For including specific functions:
@@ -39,6 +111,15 @@ or excluding some:
 xcover run --path EXE_PATH --exclude "^runtime.|^internal"
 ```
 
+### C++ and Rust functions
+
+The names of C++ and Rust functions are demangled in the reports (see `--demangle`), like `foo::Bar::run()` for `_ZN3foo3Bar3runEv`.
+By default the filters match the raw (mangled) names; for matching the demangled ones:
+
+```shell
+xcover run --path EXE_PATH --match-demangled --include "^foo::Bar::"
+```
+
 ## Daemon mode
 
 You can run the profiler as daemon with the `--detach` flag:
@@ -61,6 +142,43 @@ $ xcover stop
 xcover is stopped
 ```
 
+### Runtime directory
+
+The PID file, the socket and the log file of the daemon are stored in `/tmp` by default.
+To run more profilers in parallel, like in concurrent CI pipelines, set a different runtime directory for each, with the `--runtime-dir` flag or the `XCOVER_RUNTIME_DIR` environment variable, honored by all the commands:
+
+```shell
+export XCOVER_RUNTIME_DIR=$(mktemp -d)
+xcover run --detach --path /path/to/bin --report-path $XCOVER_RUNTIME_DIR/report.json
+xcover wait
+xcover stop
+```
+
+The report path can be set with the `--report-path` flag or the `XCOVER_REPORT_PATH` environment variable too, and its extension is replaced for each [report format](#report-formats).
+
+### Sessions
+
+To profile more programs at once on the same host, run each profiler in a named session, with the `--session` flag or the `XCOVER_SESSION` environment variable.
+The PID file, the socket, the log file and the report of each session are namespaced by its name, like `xcover-NAME.sock` and `xcover-NAME-report.json`:
+
+```shell
+xcover run --detach --session api --path /path/to/api
+xcover run --detach --session worker --path /path/to/worker
+xcover wait --session api
+xcover wait --session worker
+```
+
+List the sessions and their state with the `list` command:
+
+```shell
+$ xcover list
+SESSION  STATE  PID   EXECUTABLE       COVERAGE
+api      ready  1234  /path/to/api     12.50%
+worker   ready  1240  /path/to/worker  3.10%
+```
+
+Without a session, the `default` one is used, whose files are not namespaced.
+
 ## Report
 
 A coverage report is generated by default, and can be controlled with the `run` command's `--report` flag.
@@ -68,18 +186,30 @@ A coverage report is generated by default, and can be controlled with the `run`
 The report is provided in JSON format and contains
 * the functions that have been traced
 * the functions acknowledged
+* the number of hits per function, when enabled with the `--hits` flag
+* the source file, line and compilation unit where each function is declared, when the executable contains DWARF debug info
+* the aliases of each function, that are the other symbols at the same address, traced once
 * the coverage by function percentage
 * the executable path
+* the report of each executable, when more are [traced together](#multiple-executables)
 
 ```go
 type CoverageReport struct {
-	FuncsTraced []string `json:"funcs_traced"`
-	FuncsAck    []string `json:"funcs_ack"`
-	CovByFunc   float64  `json:"cov_by_func"`
-	ExePath     string   `json:"exe_path"`
+	FuncsTraced  []string              `json:"funcs_traced"`
+	FuncsAck     []string              `json:"funcs_ack"`
+	FuncsHits    map[string]uint64     `json:"funcs_hits,omitempty"`
+	FuncsSource  map[string]FuncSource `json:"funcs_source,omitempty"`
+	FuncsAliases map[string][]string   `json:"funcs_aliases,omitempty"`
+	CovByFunc    float64               `json:"cov_by_func"`
+	ExePath      string                `json:"exe_path"`
+	BuildID      string                `json:"build_id,omitempty"`
+	Objects      []ObjectCoverage      `json:"objects,omitempty"`
+	Executables  []CoverageReport      `json:"executables,omitempty"`
 }
 ```
 
+Functions with the same name, like static functions of different compilation units, are reported separately, followed by their source location (e.g. `foo (a.c:12)`) or, when not available, their address (e.g. `foo (0x1234)`).
+
 For instance:
 
 ```shell
@@ -89,6 +219,78 @@ $ cat xcover-report.json | jq '.cov_by_func'
 15.601900739176347
 ```
 
+### Report formats
+
+Besides the default JSON format, the report can be generated with the `--report-format` flag as:
+* [LCOV](https://github.com/linux-test-project/lcov) tracefile (`lcov`), written as `xcover-report.info`
+* [Cobertura](https://cobertura.github.io/cobertura/) XML (`cobertura`), written as `xcover-report.xml`
+* self-contained HTML page (`html`), written as `xcover-report.html`, with a sortable and filterable function table and the coverage by package and by file
+
+```shell
+$ xcover run --path myapp --report-format json,lcov,cobertura
+$ genhtml xcover-report.info -o coverage
+```
+
+A report in any format can also be generated from an existing JSON report, with the `report` command:
+
+```shell
+$ xcover report --input xcover-report.json --format html
+```
+
+When the executable contains DWARF debug info, functions are grouped by the source file they are declared in.
+In the Cobertura and HTML reports, functions are grouped into packages by Go package path or C++ namespace, when available.
+
+### Merge reports
+
+Reports of the same executable, like the ones collected by tests sharded across multiple machines, can be merged with the `merge` command:
+
+```shell
+$ xcover merge --output xcover-report.json shard-1.json shard-2.json shard-3.json
+```
+
+The acknowledged functions are merged and the function hits are summed.
+The reports must refer to the same executable path and build ID.
+
+### Compare reports
+
+Two reports can be compared with the `diff` command, for instance to review a change to the test suite against the report of the main branch:
+
+```shell
+$ xcover diff main-report.json xcover-report.json
+Coverage by function: 12.50% -> 15.00% (+2.50%)
+
+Newly covered functions (1):
+  + main.fooFunction
+```
+
+The output can be printed in JSON format with `--output json`.
+
+### Coverage thresholds
+
+The coverage can be gated with thresholds, making `xcover` exit with code `2` when any threshold is not met:
+* `--min-cov`: the minimum coverage by function percentage
+* `--min-cov-pattern PATTERN=MIN`: the minimum coverage by function percentage of the functions matching a regex pattern
+* `--min-cov-package PACKAGE=MIN`: the minimum coverage by function percentage of the functions of a package
+
+Thresholds are supported by the `run` and `stop` commands, and by the `check` command on an existing report:
+
+```shell
+$ xcover check --input xcover-report.json --min-cov 60 --min-cov-package github.com/maxgio92/xcover/pkg/trace=80
+Error: coverage by function of package github.com/maxgio92/xcover/pkg/trace 72.00% is below 80.00%
+$ echo $?
+2
+```
+
+### Function hits
+
+By default the profiler only acknowledges whether a function has been called.
+To count how many times each function has been called, use the `--hits` flag:
+
+```shell
+$ xcover run --path myapp --hits
+$ cat xcover-report.json | jq '.funcs_hits | to_entries | sort_by(-.value) | .[:3]'
+```
+
 ## Synchronization
 
 It is possible to synchronize on the `xcover` readiness, meaning that userspace can proceed executing the tests because xcover is ready to trace them all.
@@ -108,6 +310,94 @@ $ xcover stop
 
 and collect the coverage as `xcover-report.json`.
 
+### Status
+
+The `status` command prints whether the profiler is running and, once ready, its tracing statistics:
+
+```shell
+$ xcover status
+xcover is running (PID 1234)
+Executable:       /path/to/bin
+Functions:        1597 attached, 0 failed, 1597 traced
+Coverage:         12.34% (197/1597 functions)
+Events consumed:  197
+Ring buffer:      0.00% used
+Uptime:           1m30s
+```
+
+Use `--output json` to get them in JSON, for scripts.
+
+### Exec mode
+
+Alternatively, the `exec` command runs the profiler, waits for it to be ready, runs your tests, and stops the profiler when they complete:
+
+```shell
+$ xcover exec --path /path/to/bin -- ./run-tests.sh
+```
+
+It exits with the exit code of the tests command, or with code `2` when the tests succeed but the coverage does not meet the [thresholds](#coverage-thresholds).
+
+Like the profilers run with `run`, it runs in a [session](#sessions), listed by the `list` command, and it does not start when a profiler is already running in the same session.
+
+### Per-test coverage
+
+To know which test called which functions, mark the name of each test before running it with the `mark` command:
+
+```shell
+$ xcover mark test1
+$ /path/to/bin test1
+$ xcover mark test2
+$ /path/to/bin test2
+```
+
+The report includes the functions acknowledged while each label was marked:
+
+```shell
+$ jq .funcs_ack_by_label xcover-report.json
+{
+  "test1": ["main.foo", "main.bar"],
+  "test2": ["main.bar"]
+}
+```
+
+Run `xcover mark` without a label to stop labeling the functions called.
+
+### Snapshot and reset
+
+While profiling, for example during long-running soak tests, the current report can be written with the `snapshot` command, without stopping the profiler:
+
+```shell
+$ xcover snapshot /path/to/report.json
+```
+
+Between test phases, the coverage can start from zero with the `reset` command, without attaching the functions again. Optionally, the report before the reset is written too:
+
+```shell
+$ xcover reset --report-path /path/to/phase1-report.json
+```
+
+### Control protocol
+
+The `wait`, `mark`, `snapshot` and `reset` commands talk to the running profiler over its socket (`xcover.sock` in the [runtime directory](#runtime-directory)), with a versioned request/response protocol.
+Each message is a JSON object prefixed by its length, as a big endian 32-bit unsigned integer:
+
+```json
+{"version": 1, "method": "snapshot", "params": {"format": "json", "path": "/path/to/report.json"}}
+{"version": 1}
+```
+
+The methods are `ready`, `status`, `coverage`, `snapshot`, `reset`, `mark` and `shutdown`. Failed requests are responded with an `error` message.
+
+Test harnesses written in Go can use the [`client`](pkg/client) package, shared with the CLI:
+
+```go
+c := client.New("/tmp/xcover.sock")
+if err := c.Ready(ctx); err != nil {
+	return err
+}
+report, err := c.Coverage(ctx)
+```
+
 ## Quickstart
 
 A full example of usage is described below:
@@ -2,10 +2,20 @@
 
 #include <bpf/bpf_helpers.h>
 #include <bpf/bpf_core_read.h>
+#include <bpf/bpf_tracing.h>
 
 /* Function trace event */
 struct event_t {
     __u64 cookie; /* Cookie is a function identifier */
+    __u32 label;  /* Label marked when the function was called, 0 means none */
+    __u32 gen;    /* Reset generation the function was reported in */
+};
+
+/* Function trace report tracking key */
+struct seen_key_t {
+    __u64 cookie; /* Function cookie */
+    __u32 label;  /* Label marked when the function was called */
+    __u32 pad;
 };
 
 /* Function trace event ring buffer */
@@ -17,13 +27,84 @@ struct {
 /* Function trace report tracking map */
 struct {
     __uint(type, BPF_MAP_TYPE_HASH);
-    __uint(max_entries, 40960); /* Maximum number of function symbols to track */
-    __type(key, u64);           /* Function cookie */
-    __type(value, u8);          /* Report marker */
+    __uint(max_entries, 262144);      /* Maximum number of function symbols to track, by label */
+    __type(key, struct seen_key_t);   /* Function cookie and label */
+    __type(value, u8);                /* Report marker */
 } seen_funcs SEC(".maps");
 
+/* Current label map */
+struct {
+    __uint(type, BPF_MAP_TYPE_ARRAY);
+    __uint(max_entries, 1);     /* Only the current label */
+    __type(key, u32);           /* Index, always 0 */
+    __type(value, u32);         /* Label, 0 means none */
+} current_label SEC(".maps");
+
+/* Reset generation map */
+struct {
+    __uint(type, BPF_MAP_TYPE_ARRAY);
+    __uint(max_entries, 1);     /* Only the current generation */
+    __type(key, u32);           /* Index, always 0 */
+    __type(value, u32);         /* Generation, incremented at each coverage reset */
+} reset_gen SEC(".maps");
+
+/* Function hit counters map */
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(max_entries, 262144);      /* Maximum number of function symbols to track */
+    __type(key, u64);                 /* Function cookie */
+    __type(value, u64);               /* Hit counter */
+} func_hits SEC(".maps");
+
+/* Process tree tracking map */
+struct {
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __uint(max_entries, 32768); /* Maximum number of processes to track */
+    __type(key, u32);           /* Process ID (TGID) */
+    __type(value, u8);          /* Traced marker */
+} traced_pids SEC(".maps");
+
+/* Cgroup filter map */
+struct {
+    __uint(type, BPF_MAP_TYPE_CGROUP_ARRAY);
+    __uint(max_entries, 1);     /* Only the target cgroup */
+    __type(key, u32);           /* Index, always 0 */
+    __type(value, u32);         /* Cgroup v2 directory file descriptor */
+} target_cgroup SEC(".maps");
+
 long ringbuffer_flags = 0;
 
+/* Process to filter events for, 0 means all processes */
+const volatile __u32 target_pid = 0;
+
+/* Whether to filter events for the processes in the traced_pids map */
+const volatile bool filter_tree = false;
+
+/* Whether to filter events for the tasks in the target_cgroup hierarchy */
+const volatile bool filter_cgroup = false;
+
+/* Whether to count the hits of each function */
+const volatile bool count_hits = false;
+
+static __always_inline void count_hit(__u64 cookie) {
+	__u64 *hits, init = 1;
+
+	hits = bpf_map_lookup_elem(&func_hits, &cookie);
+	if (hits) {
+		__sync_fetch_and_add(hits, 1);
+
+		return;
+	}
+
+	/* The counter might have been created meanwhile by another CPU */
+	if (bpf_map_update_elem(&func_hits, &cookie, &init, BPF_NOEXIST)) {
+		hits = bpf_map_lookup_elem(&func_hits, &cookie);
+		if (hits) {
+			__sync_fetch_and_add(hits, 1);
+		}
+	}
+}
+
 SEC("uprobe/handle_user_function")
 int handle_user_function(struct pt_regs *ctx) {
 	__u64 cookie = bpf_get_attach_cookie(ctx);
@@ -31,15 +112,57 @@ int handle_user_function(struct pt_regs *ctx) {
 
 	bpf_printk("handle user function with cookie %llu\n", cookie);
 
+	/* Ignore events from processes other than the target one */
+	if (target_pid && (bpf_get_current_pid_tgid() >> 32) != target_pid) {
+		return 0;
+	}
+
+	/* Ignore events from processes out of the traced process tree */
+	if (filter_tree) {
+		__u32 tgid = bpf_get_current_pid_tgid() >> 32;
+
+		if (!bpf_map_lookup_elem(&traced_pids, &tgid)) {
+			return 0;
+		}
+	}
+
+	/* Ignore events from tasks out of the target cgroup hierarchy */
+	if (filter_cgroup && bpf_current_task_under_cgroup(&target_cgroup, 0) != 1) {
+		return 0;
+	}
+
+	if (count_hits) {
+		count_hit(cookie);
+	}
+
+	/* Functions are reported once per label */
+	struct seen_key_t key = {};
+	__u32 index = 0;
+	__u32 *label = bpf_map_lookup_elem(&current_label, &index);
+
+	key.cookie = cookie;
+	if (label) {
+		key.label = *label;
+	}
+
 	/* Check if the function has been already reported */
-	if (bpf_map_lookup_elem(&seen_funcs, &cookie)) {
+	if (bpf_map_lookup_elem(&seen_funcs, &key)) {
 		bpf_printk("function with cookie %llu already reported, skipping\n", cookie);
 
 		return 0;
 	}
 
-	/* Track which functions have been reported */
-	bpf_map_update_elem(&seen_funcs, &cookie, &seen, BPF_ANY);
+	/*
+	 * Track which functions have been reported. When the map is full, do
+	 * not report the functions not tracked, or they would be reported at
+	 * each call, flooding the ring buffer. Neither report the ones tracked
+	 * meanwhile by a concurrent call.
+	 */
+	if (bpf_map_update_elem(&seen_funcs, &key, &seen, BPF_NOEXIST)) {
+		bpf_printk("function with cookie %llu not tracked, skipping\n", cookie);
+
+		return 0;
+	}
 
 	struct event_t *event = bpf_ringbuf_reserve(&events, sizeof(struct event_t), 0);
 	if (!event) {
@@ -48,11 +171,52 @@ int handle_user_function(struct pt_regs *ctx) {
 		return 0;
 	}
 
+	/* Events of the previous generations are discarded after a reset */
+	__u32 *gen = bpf_map_lookup_elem(&reset_gen, &index);
+
 	event->cookie = cookie;
+	event->label = key.label;
+	event->gen = gen ? *gen : 0;
 	bpf_ringbuf_submit(event, ringbuffer_flags);
 	bpf_printk("submitted event to ring buffer for user function with cookie %s\n", cookie);
 
 	return 0;
 }
 
+/* Track the child processes of the traced processes */
+SEC("tp_btf/sched_process_fork")
+int BPF_PROG(handle_process_fork, struct task_struct *parent, struct task_struct *child) {
+	__u32 parent_tgid = parent->tgid;
+	__u32 child_tgid = child->tgid;
+	u8 traced = 1;
+
+	/* Ignore new threads */
+	if (parent_tgid == child_tgid) {
+		return 0;
+	}
+
+	if (!bpf_map_lookup_elem(&traced_pids, &parent_tgid)) {
+		return 0;
+	}
+
+	bpf_map_update_elem(&traced_pids, &child_tgid, &traced, BPF_ANY);
+
+	return 0;
+}
+
+/* Untrack the traced processes on exit */
+SEC("tp_btf/sched_process_exit")
+int BPF_PROG(handle_process_exit, struct task_struct *task) {
+	__u32 tgid = task->tgid;
+
+	/* Ignore threads other than the thread group leader */
+	if (task->pid != task->tgid) {
+		return 0;
+	}
+
+	bpf_map_delete_elem(&traced_pids, &tgid);
+
+	return 0;
+}
+
 char __license[] SEC("license") = "GPL";
@@ -1,10 +1,137 @@
 package settings
 
-import "fmt"
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"sort"
+	"strings"
 
-const CmdName = "xcover"
+	"github.com/pkg/errors"
+)
+
+const (
+	CmdName = "xcover"
+
+	// DefaultRuntimeDir is the default directory of the PID file, the socket
+	// and the log file.
+	DefaultRuntimeDir = "/tmp"
+	// RuntimeDirEnv is the environment variable to set the runtime directory.
+	RuntimeDirEnv = "XCOVER_RUNTIME_DIR"
+	// ReportPathEnv is the environment variable to set the report path.
+	ReportPathEnv = "XCOVER_REPORT_PATH"
+
+	// DefaultSession is the name of the session whose files are not
+	// namespaced.
+	DefaultSession = "default"
+	// SessionEnv is the environment variable to set the session.
+	SessionEnv = "XCOVER_SESSION"
+)
 
 var (
-	PidFile   = fmt.Sprintf("/tmp/%s.pid", CmdName)
-	LogFile   = fmt.Sprintf("/tmp/%s.log", CmdName)
+	ErrSessionNameInvalid = errors.New("invalid session name")
+
+	sessionNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
 )
+
+// Version returns the version of the build, empty when unknown,
+// like for the builds from a working tree.
+func Version() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Version == "(devel)" {
+		return ""
+	}
+
+	return info.Main.Version
+}
+
+// RuntimeDir returns the runtime directory set with the environment,
+// or the default one.
+func RuntimeDir() string {
+	if dir := os.Getenv(RuntimeDirEnv); dir != "" {
+		return dir
+	}
+
+	return DefaultRuntimeDir
+}
+
+// ReportPath returns the report path set with the environment, if any.
+func ReportPath() string {
+	return os.Getenv(ReportPathEnv)
+}
+
+// Session returns the session set with the environment, or the default one.
+func Session() string {
+	if session := os.Getenv(SessionEnv); session != "" {
+		return session
+	}
+
+	return DefaultSession
+}
+
+// ValidateSession returns an error if the session name can't name its files.
+func ValidateSession(session string) error {
+	if !sessionNameRegexp.MatchString(session) {
+		return errors.Wrap(ErrSessionNameInvalid, session)
+	}
+
+	return nil
+}
+
+// SessionFileName returns the base name of the files of the session.
+func SessionFileName(session string) string {
+	if session == DefaultSession {
+		return CmdName
+	}
+
+	return fmt.Sprintf("%s-%s", CmdName, session)
+}
+
+// PidFile returns the path of the PID file of the session in the runtime
+// directory.
+func PidFile(runtimeDir, session string) string {
+	return filepath.Join(runtimeDir, SessionFileName(session)+".pid")
+}
+
+// LogFile returns the path of the log file of the session in the runtime
+// directory.
+func LogFile(runtimeDir, session string) string {
+	return filepath.Join(runtimeDir, SessionFileName(session)+".log")
+}
+
+// SockFile returns the path of the socket file of the session in the runtime
+// directory.
+func SockFile(runtimeDir, session string) string {
+	return filepath.Join(runtimeDir, SessionFileName(session)+".sock")
+}
+
+// Sessions returns the sorted names of the sessions with a PID file in the
+// runtime directory.
+func Sessions(runtimeDir string) ([]string, error) {
+	entries, err := os.ReadDir(runtimeDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read the runtime directory")
+	}
+
+	var sessions []string
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".pid")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if name == CmdName {
+			sessions = append(sessions, DefaultSession)
+			continue
+		}
+		session, ok := strings.CutPrefix(name, CmdName+"-")
+		if !ok || ValidateSession(session) != nil || session == DefaultSession {
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	sort.Strings(sessions)
+
+	return sessions, nil
+}
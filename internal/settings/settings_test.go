@@ -0,0 +1,40 @@
+package settings_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxgio92/xcover/internal/settings"
+)
+
+func TestValidateSession(t *testing.T) {
+	require.NoError(t, settings.ValidateSession("job-1.2_a"))
+	require.ErrorIs(t, settings.ValidateSession(""), settings.ErrSessionNameInvalid)
+	require.ErrorIs(t, settings.ValidateSession("../foo"), settings.ErrSessionNameInvalid)
+	require.ErrorIs(t, settings.ValidateSession("-foo"), settings.ErrSessionNameInvalid)
+}
+
+func TestSessionFiles(t *testing.T) {
+	require.Equal(t, "/run/xcover.pid", settings.PidFile("/run", settings.DefaultSession))
+	require.Equal(t, "/run/xcover-foo.pid", settings.PidFile("/run", "foo"))
+	require.Equal(t, "/run/xcover-foo.sock", settings.SockFile("/run", "foo"))
+	require.Equal(t, "/run/xcover-foo.log", settings.LogFile("/run", "foo"))
+}
+
+func TestSessions(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"xcover.pid", "xcover-foo.pid", "xcover-bar.pid", "xcover-foo.sock", "xcover-default.pid", "other.pid"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+
+	sessions, err := settings.Sessions(dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{"bar", settings.DefaultSession, "foo"}, sessions)
+}
+
+func TestVersion(t *testing.T) {
+	require.NotEqual(t, "(devel)", settings.Version())
+}
@@ -0,0 +1,97 @@
+package cgroup
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// Root is the default mount point of the cgroup v2 hierarchy.
+	Root = "/sys/fs/cgroup"
+	// cgroup2SuperMagic is the magic number of the cgroup v2 file system.
+	cgroup2SuperMagic = 0x63677270
+)
+
+var (
+	ErrCgroupNotFound = errors.New("cgroup not found")
+	ErrNotCgroup2     = errors.New("not a cgroup v2 directory")
+)
+
+// Resolve returns the path of the cgroup under the root of the cgroup v2
+// hierarchy. The cgroup is either its ID, its path relative to the root,
+// or its absolute path under the root.
+func Resolve(root, cgroup string) (string, error) {
+	if id, err := strconv.ParseUint(cgroup, 10, 64); err == nil {
+		return findByID(root, id)
+	}
+
+	path := cgroup
+	if !strings.HasPrefix(filepath.Clean(path), filepath.Clean(root)+string(filepath.Separator)) {
+		path = filepath.Join(root, path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", errors.Wrapf(ErrCgroupNotFound, "%s", cgroup)
+	}
+	if !info.IsDir() {
+		return "", errors.Wrapf(ErrNotCgroup2, "%s", path)
+	}
+
+	return path, nil
+}
+
+// ID returns the ID of the cgroup, that is the inode number of its
+// directory in the cgroup v2 hierarchy.
+func ID(path string) (uint64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, errors.Errorf("failed to get the inode of %s", path)
+	}
+
+	return stat.Ino, nil
+}
+
+// IsCgroup2 returns whether the path is in a cgroup v2 file system.
+func IsCgroup2(path string) (bool, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return false, errors.Wrapf(err, "failed to stat file system of %s", path)
+	}
+
+	return st.Type == cgroup2SuperMagic, nil
+}
+
+// findByID walks the cgroup hierarchy and returns the path of the cgroup
+// with the ID.
+func findByID(root string, id uint64) (string, error) {
+	var found string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		// The cgroups can be removed meanwhile.
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if cgID, err := ID(path); err == nil && cgID == id {
+			found = path
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to walk %s", root)
+	}
+	if found == "" {
+		return "", errors.Wrapf(ErrCgroupNotFound, "id %d", id)
+	}
+
+	return found, nil
+}
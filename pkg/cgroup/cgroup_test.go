@@ -0,0 +1,45 @@
+package cgroup_test
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxgio92/xcover/pkg/cgroup"
+)
+
+func TestResolve(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "system.slice", "test.scope")
+	require.NoError(t, os.MkdirAll(path, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(path, "cgroup.procs"), nil, 0644))
+
+	got, err := cgroup.Resolve(root, "/system.slice/test.scope")
+	require.NoError(t, err)
+	require.Equal(t, path, got)
+
+	got, err = cgroup.Resolve(root, path)
+	require.NoError(t, err)
+	require.Equal(t, path, got)
+
+	id, err := cgroup.ID(path)
+	require.NoError(t, err)
+	got, err = cgroup.Resolve(root, strconv.FormatUint(id, 10))
+	require.NoError(t, err)
+	require.Equal(t, path, got)
+
+	_, err = cgroup.Resolve(root, "/nonexistent.scope")
+	require.ErrorIs(t, err, cgroup.ErrCgroupNotFound)
+
+	_, err = cgroup.Resolve(root, "/system.slice/test.scope/cgroup.procs")
+	require.ErrorIs(t, err, cgroup.ErrNotCgroup2)
+}
+
+func TestIsCgroup2(t *testing.T) {
+	ok, err := cgroup.IsCgroup2(t.TempDir())
+	require.NoError(t, err)
+	require.False(t, ok)
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/maxgio92/xcover/pkg/coverage"
+	"github.com/maxgio92/xcover/pkg/healthcheck"
+)
+
+// DefaultTimeout is the default timeout of the requests.
+const DefaultTimeout = 30 * time.Second
+
+// Client sends requests to a running tracer over its socket.
+type Client struct {
+	socketPath string
+	timeout    time.Duration
+}
+
+type Option func(*Client)
+
+// WithTimeout sets the timeout of each request, except the readiness ones
+// that are bound to their context.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// New returns a client of the tracer listening at the socket path.
+func New(socketPath string, opts ...Option) *Client {
+	c := &Client{
+		socketPath: socketPath,
+		timeout:    DefaultTimeout,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Ready waits for the tracer to be ready, until the context is done.
+func (c *Client) Ready(ctx context.Context) error {
+	var result healthcheck.ReadyResult
+	if err := c.do(ctx, healthcheck.MethodReady, nil, &result); err != nil {
+		return err
+	}
+	if !result.Ready {
+		return healthcheck.ErrNotReady
+	}
+
+	return nil
+}
+
+// Status returns the status of the tracer.
+func (c *Client) Status(ctx context.Context) (*healthcheck.Status, error) {
+	status := new(healthcheck.Status)
+	if err := c.doTimeout(ctx, healthcheck.MethodStatus, nil, status); err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// Coverage returns the current coverage report of the tracer.
+func (c *Client) Coverage(ctx context.Context) (*coverage.CoverageReport, error) {
+	report := new(coverage.CoverageReport)
+	if err := c.doTimeout(ctx, healthcheck.MethodCoverage, nil, report); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// Snapshot makes the tracer write the current report in the format to the
+// path, while it keeps tracing.
+func (c *Client) Snapshot(ctx context.Context, format coverage.ReportFormat, path string) error {
+	params := &healthcheck.ReportParams{Format: format, Path: path}
+
+	return c.doTimeout(ctx, healthcheck.MethodSnapshot, params, nil)
+}
+
+// Reset resets the coverage of the tracer. When the path is not empty,
+// the report before the reset is written in the format to it.
+func (c *Client) Reset(ctx context.Context, format coverage.ReportFormat, path string) error {
+	params := &healthcheck.ReportParams{Format: format, Path: path}
+
+	return c.doTimeout(ctx, healthcheck.MethodReset, params, nil)
+}
+
+// Mark sets the label of the functions called from now on, empty meaning none.
+func (c *Client) Mark(ctx context.Context, label string) error {
+	params := &healthcheck.MarkParams{Label: label}
+
+	return c.doTimeout(ctx, healthcheck.MethodMark, params, nil)
+}
+
+// Shutdown stops the tracer, that writes the report.
+func (c *Client) Shutdown(ctx context.Context) error {
+	return c.doTimeout(ctx, healthcheck.MethodShutdown, nil, nil)
+}
+
+func (c *Client) doTimeout(ctx context.Context, method healthcheck.Method, params, result any) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	return c.do(ctx, method, params, result)
+}
+
+// do sends the request and decodes the result of the response into result,
+// if not nil.
+func (c *Client) do(ctx context.Context, method healthcheck.Method, params, result any) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", c.socketPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to connect")
+	}
+	defer conn.Close()
+
+	// Unblock the pending read or write when the context is done.
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	req := &healthcheck.Request{
+		Version: healthcheck.ProtocolVersion,
+		Method:  method,
+	}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return errors.Wrap(err, "failed to encode params")
+		}
+		req.Params = data
+	}
+
+	if err := healthcheck.WriteMessage(conn, req); err != nil {
+		return errors.Wrapf(err, "failed to send %s request", method)
+	}
+
+	var resp healthcheck.Response
+	if err := healthcheck.ReadMessage(conn, &resp); err != nil {
+		if ctx.Err() != nil {
+			return errors.Wrapf(ctx.Err(), "failed to receive %s response", method)
+		}
+		return errors.Wrapf(err, "failed to receive %s response", method)
+	}
+	switch resp.Error {
+	case "":
+	case healthcheck.ErrNotReady.Error():
+		return healthcheck.ErrNotReady
+	default:
+		return errors.New(resp.Error)
+	}
+	if result != nil && len(resp.Result) > 0 {
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return errors.Wrapf(err, "failed to decode %s result", method)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package client_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxgio92/xcover/pkg/client"
+	"github.com/maxgio92/xcover/pkg/coverage"
+	"github.com/maxgio92/xcover/pkg/healthcheck"
+)
+
+type handler struct {
+	labels []string
+	resets []string
+}
+
+func (h *handler) Status() (*healthcheck.Status, error) {
+	return &healthcheck.Status{Pid: 1234, ExePath: "mybin"}, nil
+}
+
+func (h *handler) Coverage() (*coverage.CoverageReport, error) {
+	return coverage.NewCoverageReport(coverage.WithReportExePath("mybin")), nil
+}
+
+func (h *handler) Snapshot(_ coverage.ReportFormat, _ string) error {
+	return nil
+}
+
+func (h *handler) Reset(format coverage.ReportFormat, path string) error {
+	h.resets = append(h.resets, string(format)+":"+path)
+	return nil
+}
+
+func (h *handler) Mark(label string) error {
+	h.labels = append(h.labels, label)
+	return nil
+}
+
+func (h *handler) Shutdown() error {
+	return nil
+}
+
+func TestClient(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	socketPath := filepath.Join(t.TempDir(), "test.sock")
+	h := new(handler)
+	server := healthcheck.NewHealthCheckServer(socketPath, zerolog.Nop(), healthcheck.WithHandler(h))
+	require.NoError(t, server.InitializeListener(ctx))
+	defer server.ShutdownListener()
+
+	c := client.New(socketPath, client.WithTimeout(time.Second))
+
+	// Not ready yet.
+	readyCtx, readyCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer readyCancel()
+	require.ErrorIs(t, c.Ready(readyCtx), context.DeadlineExceeded)
+	_, err := c.Status(ctx)
+	require.ErrorIs(t, err, healthcheck.ErrNotReady)
+
+	server.NotifyReadiness()
+	require.NoError(t, c.Ready(ctx))
+
+	status, err := c.Status(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "mybin", status.ExePath)
+
+	report, err := c.Coverage(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "mybin", report.ExePath)
+
+	require.NoError(t, c.Mark(ctx, "test1"))
+	require.Equal(t, []string{"test1"}, h.labels)
+
+	require.NoError(t, c.Reset(ctx, coverage.ReportFormatJSON, "/tmp/report.json"))
+	require.Equal(t, []string{"json:/tmp/report.json"}, h.resets)
+
+	require.Error(t, c.Snapshot(ctx, coverage.ReportFormatJSON, ""))
+	require.NoError(t, c.Shutdown(ctx))
+}
@@ -0,0 +1,61 @@
+package check
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/maxgio92/xcover/pkg/cmd/common"
+	"github.com/maxgio92/xcover/pkg/cmd/options"
+	"github.com/maxgio92/xcover/pkg/coverage"
+)
+
+const CmdName = "check"
+
+type Options struct {
+	input string
+
+	common.ThresholdOptions
+	*options.Options
+}
+
+func NewCommand(opts *options.Options) *cobra.Command {
+	o := new(Options)
+	o.Options = opts
+	cmd := &cobra.Command{
+		Use:   CmdName,
+		Short: "Check a coverage report against coverage thresholds",
+		Long: fmt.Sprintf(`
+%s checks the coverage by function of an existing JSON report against the specified thresholds,
+for all the functions, for the functions matching a pattern, or for the functions of a package.
+It exits with code %d when any threshold is not met.
+`, CmdName, common.ExitCodeThreshold),
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		RunE:              o.Run,
+	}
+
+	cmd.Flags().StringVarP(&o.input, "input", "i", "", "Path to the JSON report (default to the one of the session)")
+	o.ThresholdOptions.AddFlags(cmd.Flags())
+
+	return cmd
+}
+
+func (o *Options) Run(_ *cobra.Command, _ []string) error {
+	thresholds, err := o.Thresholds()
+	if err != nil {
+		return err
+	}
+
+	report, err := coverage.ReadReportFile(common.ReportPath(o.input, o.Session))
+	if err != nil {
+		return err
+	}
+
+	if err := common.CheckThresholds(report, thresholds); err != nil {
+		return err
+	}
+	fmt.Printf("coverage by function: %.2f%%\n", report.CovByFunc)
+
+	return nil
+}
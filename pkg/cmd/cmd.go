@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -11,8 +12,18 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/maxgio92/xcover/internal/settings"
+	"github.com/maxgio92/xcover/pkg/cmd/check"
+	"github.com/maxgio92/xcover/pkg/cmd/common"
+	"github.com/maxgio92/xcover/pkg/cmd/diff"
+	"github.com/maxgio92/xcover/pkg/cmd/exec"
+	"github.com/maxgio92/xcover/pkg/cmd/list"
+	"github.com/maxgio92/xcover/pkg/cmd/mark"
+	"github.com/maxgio92/xcover/pkg/cmd/merge"
 	"github.com/maxgio92/xcover/pkg/cmd/options"
+	"github.com/maxgio92/xcover/pkg/cmd/report"
+	"github.com/maxgio92/xcover/pkg/cmd/reset"
 	"github.com/maxgio92/xcover/pkg/cmd/run"
+	"github.com/maxgio92/xcover/pkg/cmd/snapshot"
 	"github.com/maxgio92/xcover/pkg/cmd/status"
 	"github.com/maxgio92/xcover/pkg/cmd/stop"
 	"github.com/maxgio92/xcover/pkg/cmd/wait"
@@ -29,8 +40,12 @@ Run the '%s' command to run the profiler that will trace all the functions of th
 Wait for the profiler to be ready before running your tests, with the '%s' command.
 Once the profiler is ready to trace all the functions, you can start running your tests.
 At the end of your tests, the profiler can be stopped and a report being collected.
+Alternatively, run your tests with the '%s' command, that does all of the above.
+To know which test called which functions, mark the name of each test before running it, with the '%s' command.
+While profiling, write the current report with the '%s' command, and start from zero with the '%s' command.
+To profile more programs in parallel, run each profiler in its own session with the '--session' flag, and list the sessions with the '%s' command.
 `,
-			settings.CmdName, run.CmdName, wait.CmdName),
+			settings.CmdName, run.CmdName, wait.CmdName, exec.CmdName, mark.CmdName, snapshot.CmdName, reset.CmdName, list.CmdName),
 		DisableAutoGenTag: true,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			logLevelS, err := cmd.Flags().GetString("log-level")
@@ -44,15 +59,27 @@ At the end of your tests, the profiler can be stopped and a report being collect
 				o.Logger.Fatal().Err(err).Msg("invalid log level")
 			}
 			o.Logger = o.Logger.Level(logLevel)
-			return nil
+
+			return settings.ValidateSession(o.Session)
 		},
 	}
 	cmd.PersistentFlags().StringVar(&o.LogLevel, "log-level", log.LevelInfoValue, "Log level (trace, debug, info, warn, error, fatal, panic)")
+	cmd.PersistentFlags().StringVar(&o.Session, "session", settings.Session(), fmt.Sprintf("Name of the profiling session, to run more profilers in parallel, also set with %s", settings.SessionEnv))
+	cmd.PersistentFlags().StringVar(&o.RuntimeDir, "runtime-dir", settings.RuntimeDir(), fmt.Sprintf("Directory of the PID file, the socket and the log file of the profiler, also set with %s", settings.RuntimeDirEnv))
 
 	cmd.AddCommand(run.NewCommand(o))
 	cmd.AddCommand(wait.NewCommand(o))
 	cmd.AddCommand(status.NewCommand(o))
 	cmd.AddCommand(stop.NewCommand(o))
+	cmd.AddCommand(list.NewCommand(o))
+	cmd.AddCommand(exec.NewCommand(o))
+	cmd.AddCommand(mark.NewCommand(o))
+	cmd.AddCommand(snapshot.NewCommand(o))
+	cmd.AddCommand(reset.NewCommand(o))
+	cmd.AddCommand(report.NewCommand(o))
+	cmd.AddCommand(merge.NewCommand(o))
+	cmd.AddCommand(diff.NewCommand(o))
+	cmd.AddCommand(check.NewCommand(o))
 
 	return cmd
 }
@@ -75,6 +102,10 @@ func Execute() {
 	)
 
 	if err := NewCommand(opts).Execute(); err != nil {
+		var exitErr *common.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		os.Exit(1)
 	}
 }
@@ -1,15 +1,19 @@
 package common
 
 import (
+	"fmt"
 	"os"
 	"strconv"
 	"syscall"
 
 	"github.com/maxgio92/xcover/internal/settings"
+	"github.com/maxgio92/xcover/pkg/coverage"
+	"github.com/maxgio92/xcover/pkg/trace"
 )
 
-func IsDaemonRunning() bool {
-	pidData, err := os.ReadFile(settings.PidFile)
+// IsDaemonRunning returns whether the process of the PID file is running.
+func IsDaemonRunning(pidFile string) bool {
+	pidData, err := os.ReadFile(pidFile)
 	if err != nil {
 		return false
 	}
@@ -27,3 +31,19 @@ func IsDaemonRunning() bool {
 	// Check if process exists
 	return process.Signal(syscall.Signal(0)) == nil
 }
+
+// ReportPath returns the path, when not empty, the path of the JSON report
+// set with the environment, or the default one of the session.
+func ReportPath(path, session string) string {
+	if path != "" {
+		return path
+	}
+	if path := settings.ReportPath(); path != "" {
+		return trace.ReportPathWithFormat(path, coverage.ReportFormatJSON)
+	}
+	if session == settings.DefaultSession {
+		return trace.ReportFileName
+	}
+
+	return fmt.Sprintf("%s-report.%s", settings.SessionFileName(session), coverage.ReportFormatJSON.FileExt())
+}
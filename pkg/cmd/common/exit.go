@@ -0,0 +1,46 @@
+package common
+
+import (
+	"os/exec"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ExitCodeThreshold is the exit code when the coverage does not meet the thresholds.
+	ExitCodeThreshold = 2
+	// exitCodeSignaled is the base exit code of a command terminated by a
+	// signal, like shells do.
+	exitCodeSignaled = 128
+)
+
+// ExitError is an error that makes the command exit with a specific code.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// CommandExitError wraps an *exec.ExitError into an *ExitError with the exit
+// code of the command. Other errors are returned as they are.
+func CommandExitError(err error) error {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return err
+	}
+
+	code := exitErr.ExitCode()
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		code = exitCodeSignaled + int(status.Signal())
+	}
+
+	return &ExitError{Code: code, Err: err}
+}
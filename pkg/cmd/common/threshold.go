@@ -0,0 +1,81 @@
+package common
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+
+	"github.com/maxgio92/xcover/pkg/coverage"
+)
+
+// ThresholdOptions are the options to gate the coverage with thresholds.
+type ThresholdOptions struct {
+	minCov         float64
+	minCovPatterns []string
+	minCovPackages []string
+}
+
+// AddFlags adds the threshold flags to the flag set.
+func (o *ThresholdOptions) AddFlags(flags *pflag.FlagSet) {
+	flags.Float64Var(&o.minCov, "min-cov", 0, "Minimum coverage by function percentage")
+	flags.StringArrayVar(&o.minCovPatterns, "min-cov-pattern", nil, "Minimum coverage by function percentage of the functions matching a regex pattern, as PATTERN=MIN (can be repeated)")
+	flags.StringArrayVar(&o.minCovPackages, "min-cov-package", nil, "Minimum coverage by function percentage of the functions of a package, as PACKAGE=MIN (can be repeated)")
+}
+
+// Args returns the command line arguments of the threshold flags, to check
+// the thresholds in another process.
+func (o *ThresholdOptions) Args() []string {
+	args := []string{fmt.Sprintf("--min-cov=%v", o.minCov)}
+	for _, v := range o.minCovPatterns {
+		args = append(args, fmt.Sprintf("--min-cov-pattern=%s", v))
+	}
+	for _, v := range o.minCovPackages {
+		args = append(args, fmt.Sprintf("--min-cov-package=%s", v))
+	}
+
+	return args
+}
+
+// Thresholds returns the thresholds set with the flags.
+func (o *ThresholdOptions) Thresholds() ([]coverage.Threshold, error) {
+	var thresholds []coverage.Threshold
+	if o.minCov > 0 {
+		thresholds = append(thresholds, coverage.Threshold{Scope: coverage.ThresholdScopeAll, Min: o.minCov})
+	}
+
+	for _, scoped := range []struct {
+		scope  coverage.ThresholdScope
+		values []string
+	}{
+		{coverage.ThresholdScopePattern, o.minCovPatterns},
+		{coverage.ThresholdScopePackage, o.minCovPackages},
+	} {
+		for _, v := range scoped.values {
+			threshold, err := coverage.ParseThreshold(scoped.scope, v)
+			if err != nil {
+				return nil, err
+			}
+			thresholds = append(thresholds, threshold)
+		}
+	}
+
+	return thresholds, nil
+}
+
+// CheckThresholds returns an *ExitError with ExitCodeThreshold code if the
+// report does not meet the thresholds.
+func CheckThresholds(report *coverage.CoverageReport, thresholds []coverage.Threshold) error {
+	return ThresholdExitError(report.CheckThresholds(thresholds...))
+}
+
+// ThresholdExitError wraps a *coverage.ThresholdError into an *ExitError with
+// ExitCodeThreshold code. Other errors are returned as they are.
+func ThresholdExitError(err error) error {
+	var thErr *coverage.ThresholdError
+	if errors.As(err, &thErr) {
+		return &ExitError{Code: ExitCodeThreshold, Err: thErr}
+	}
+
+	return err
+}
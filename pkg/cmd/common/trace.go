@@ -0,0 +1,190 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+
+	"github.com/maxgio92/xcover/internal/settings"
+	"github.com/maxgio92/xcover/pkg/cmd/options"
+	"github.com/maxgio92/xcover/pkg/coverage"
+	"github.com/maxgio92/xcover/pkg/trace"
+)
+
+var (
+	ErrDebugFileWithPaths = errors.New("the debug file cannot be set when tracing more executables")
+	ErrPidWithPaths       = errors.New("the pid filter cannot be set when tracing more executables")
+)
+
+// TraceOptions are the options to trace the functions of a program.
+type TraceOptions struct {
+	paths     []string
+	debugFile string
+	libs      []string
+	pid       int
+	cgroup    string
+
+	// launch makes the tracer start the program with args.
+	launch bool
+	args   []string
+
+	neededLibs bool
+
+	followLibs         string
+	followLibsInterval time.Duration
+
+	symExcludePattern string
+	symIncludePattern string
+	demangle          bool
+	matchDemangled    bool
+
+	verbose bool
+	report  bool
+	hits    bool
+	status  bool
+
+	reportFormats []string
+	reportPath    string
+
+	ThresholdOptions
+}
+
+// AddFlags adds the trace flags to the flag set.
+func (o *TraceOptions) AddFlags(flags *pflag.FlagSet) {
+	flags.StringSliceVarP(&o.paths, "path", "p", nil, "Path to the ELF executable, repeatable to trace more executables together, like cooperating programs")
+	flags.StringVar(&o.debugFile, "debug-file", "", "Path to the separate debug info file of the ELF executable, to read the symbols from")
+	flags.StringSliceVar(&o.libs, "lib", nil, "Path to a shared library to trace the functions of, along with the executable ones")
+	flags.BoolVar(&o.neededLibs, "needed-libs", false, "Trace the functions of the shared libraries needed by the executable")
+	flags.StringVar(&o.followLibs, "follow-libs", "", "Regex pattern of the paths of the shared libraries to trace when loaded at runtime by the program, like with dlopen")
+	flags.DurationVar(&o.followLibsInterval, "follow-libs-interval", trace.DefaultFollowLibsInterval, "Interval to look up the shared libraries loaded at runtime")
+	flags.IntVar(&o.pid, "pid", -1, "Filter the process by PID")
+	flags.StringVar(&o.cgroup, "cgroup", "", "Filter the processes in the cgroup v2 hierarchy, by ID or path, like the one of a container")
+
+	flags.StringVar(&o.symExcludePattern, "exclude", "", "Regex pattern to exclude function symbol names")
+	flags.StringVar(&o.symIncludePattern, "include", "", "Regex pattern to include function symbol names")
+	flags.BoolVar(&o.demangle, "demangle", true, "Demangle the C++ and Rust function names")
+	flags.BoolVar(&o.matchDemangled, "match-demangled", false, "Match the include and exclude patterns against the demangled function names")
+
+	flags.BoolVar(&o.verbose, "verbose", false, "Enable verbosity")
+	flags.BoolVar(&o.report, "report", true, fmt.Sprintf("Generate report (as %s)", trace.ReportFileName))
+	flags.StringSliceVar(&o.reportFormats, "report-format", []string{string(coverage.ReportFormatJSON)}, fmt.Sprintf("Report formats (%s)", strings.Join(coverage.ReportFormatNames(), ", ")))
+	flags.StringVar(&o.reportPath, "report-path", "", fmt.Sprintf("Path to the report, whose extension is replaced for each format (default %s, or %s-SESSION-report.json in a session), also set with %s", trace.ReportFileName, settings.CmdName, settings.ReportPathEnv))
+	flags.BoolVar(&o.hits, "hits", false, "Count the hits of each function in the report")
+	flags.BoolVar(&o.status, "status", true, "Periodically print a status of the trace")
+
+	o.ThresholdOptions.AddFlags(flags)
+}
+
+// SetLaunchArgs makes the tracer start the program with the arguments, and
+// trace only its process tree.
+func (o *TraceOptions) SetLaunchArgs(args []string) {
+	o.launch = true
+	o.args = args
+}
+
+// NewTracer returns the tracer of the program with the options set with the flags,
+// in the session of the command options.
+func (o *TraceOptions) NewTracer(cmdOpts *options.Options) (*trace.UserTracer, error) {
+	reportFormats, err := o.ReportFormats()
+	if err != nil {
+		return nil, err
+	}
+	thresholds, err := o.Thresholds()
+	if err != nil {
+		return nil, err
+	}
+
+	if o.debugFile != "" && len(o.paths) > 1 {
+		return nil, ErrDebugFileWithPaths
+	}
+	if o.pid > 0 && len(o.paths) > 1 {
+		return nil, ErrPidWithPaths
+	}
+
+	opts := []trace.UserTracerOpt{
+		trace.WithTracerLogger(cmdOpts.Logger),
+		trace.WithTracerVerbose(o.verbose),
+		trace.WithTracerReport(o.report),
+		trace.WithTracerReportFormats(reportFormats...),
+		trace.WithTracerReportPath(ReportPath(o.reportPath, cmdOpts.Session)),
+		trace.WithTracerSocketPath(cmdOpts.SocketPath("")),
+		trace.WithTracerThresholds(thresholds...),
+		trace.WithTracerHits(o.hits),
+		trace.WithTracerStatus(o.status),
+		trace.WithTracerPid(o.pid),
+		trace.WithTracerCgroup(o.cgroup),
+		trace.WithTracerFollowLibs(o.followLibs),
+		trace.WithTracerFollowLibsInterval(o.followLibsInterval),
+	}
+	for _, path := range o.paths {
+		opts = append(opts, trace.WithTracerTracee(trace.NewUserTracee(
+			trace.WithTraceeExePath(path),
+			trace.WithTraceeDebugFilePath(o.debugFile),
+			trace.WithTraceeLibPaths(o.libs...),
+			trace.WithTraceeNeededLibs(o.neededLibs),
+			trace.WithTraceeSymPatternInclude(o.symIncludePattern),
+			trace.WithTraceeSymPatternExclude(o.symExcludePattern),
+			trace.WithTraceeDemangle(o.demangle),
+			trace.WithTraceeSymMatchDemangled(o.matchDemangled),
+			trace.WithTraceeLogger(cmdOpts.Logger),
+		)))
+	}
+	if o.launch {
+		opts = append(opts, trace.WithTracerLaunch(o.args...))
+	}
+
+	return trace.NewUserTracer(opts...), nil
+}
+
+// Args returns the command line arguments of the trace flags, to run
+// the tracer in another process.
+func (o *TraceOptions) Args() []string {
+	var args []string
+	for _, path := range o.paths {
+		args = append(args, fmt.Sprintf("--path=%s", path))
+	}
+	args = append(args, fmt.Sprintf("--debug-file=%s", o.debugFile))
+	for _, lib := range o.libs {
+		args = append(args, fmt.Sprintf("--lib=%s", lib))
+	}
+	args = append(args, fmt.Sprintf("--needed-libs=%s", strconv.FormatBool(o.neededLibs)))
+	args = append(args, fmt.Sprintf("--follow-libs=%s", o.followLibs))
+	args = append(args, fmt.Sprintf("--follow-libs-interval=%s", o.followLibsInterval))
+	args = append(args, fmt.Sprintf("--pid=%d", o.pid))
+	args = append(args, fmt.Sprintf("--cgroup=%s", o.cgroup))
+	args = append(args, fmt.Sprintf("--exclude=%s", o.symExcludePattern))
+	args = append(args, fmt.Sprintf("--include=%s", o.symIncludePattern))
+	args = append(args, fmt.Sprintf("--demangle=%s", strconv.FormatBool(o.demangle)))
+	args = append(args, fmt.Sprintf("--match-demangled=%s", strconv.FormatBool(o.matchDemangled)))
+	args = append(args, fmt.Sprintf("--report=%s", strconv.FormatBool(o.report)))
+	args = append(args, fmt.Sprintf("--report-format=%s", strings.Join(o.reportFormats, ",")))
+	args = append(args, fmt.Sprintf("--report-path=%s", o.reportPath))
+	args = append(args, fmt.Sprintf("--hits=%s", strconv.FormatBool(o.hits)))
+	args = append(args, fmt.Sprintf("--status=%s", strconv.FormatBool(o.status)))
+	args = append(args, fmt.Sprintf("--verbose=%s", strconv.FormatBool(o.verbose)))
+	args = append(args, o.ThresholdOptions.Args()...)
+	if o.launch {
+		args = append(args, "--")
+		args = append(args, o.args...)
+	}
+
+	return args
+}
+
+// ReportFormats returns the report formats set with the flags.
+func (o *TraceOptions) ReportFormats() ([]coverage.ReportFormat, error) {
+	formats := make([]coverage.ReportFormat, 0, len(o.reportFormats))
+	for _, f := range o.reportFormats {
+		format := coverage.ReportFormat(f)
+		if err := format.Validate(); err != nil {
+			return nil, err
+		}
+		formats = append(formats, format)
+	}
+
+	return formats, nil
+}
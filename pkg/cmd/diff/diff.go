@@ -0,0 +1,70 @@
+package diff
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/maxgio92/xcover/pkg/cmd/options"
+	"github.com/maxgio92/xcover/pkg/coverage"
+)
+
+const (
+	CmdName = "diff"
+
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+var ErrOutputUnknown = errors.New("unknown output format")
+
+type Options struct {
+	output string
+
+	*options.Options
+}
+
+func NewCommand(opts *options.Options) *cobra.Command {
+	o := new(Options)
+	o.Options = opts
+	cmd := &cobra.Command{
+		Use:   fmt.Sprintf("%s BASE_REPORT HEAD_REPORT", CmdName),
+		Short: "Compare two coverage reports",
+		Long: fmt.Sprintf(`
+%s compares two JSON coverage reports, and prints the functions newly covered and uncovered,
+the functions added and removed, and the coverage delta of the head report from the base one.
+`, CmdName),
+		Args:              cobra.ExactArgs(2),
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		RunE:              o.Run,
+	}
+
+	cmd.Flags().StringVarP(&o.output, "output", "o", OutputText, fmt.Sprintf("Output format (%s, %s)", OutputText, OutputJSON))
+
+	return cmd
+}
+
+func (o *Options) Run(_ *cobra.Command, args []string) error {
+	if o.output != OutputText && o.output != OutputJSON {
+		return errors.Wrap(ErrOutputUnknown, o.output)
+	}
+
+	base, err := coverage.ReadReportFile(args[0])
+	if err != nil {
+		return errors.Wrapf(err, "failed to read report %s", args[0])
+	}
+	head, err := coverage.ReadReportFile(args[1])
+	if err != nil {
+		return errors.Wrapf(err, "failed to read report %s", args[1])
+	}
+
+	diff := coverage.Diff(base, head)
+	if o.output == OutputJSON {
+		return diff.WriteJSON(os.Stdout)
+	}
+
+	return diff.WriteText(os.Stdout)
+}
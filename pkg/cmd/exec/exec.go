@@ -0,0 +1,134 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/maxgio92/xcover/internal/settings"
+	"github.com/maxgio92/xcover/pkg/cmd/common"
+	"github.com/maxgio92/xcover/pkg/cmd/options"
+)
+
+const CmdName = "exec"
+
+var (
+	ErrTracerNotReady = fmt.Errorf("%s terminated before being ready", settings.CmdName)
+	ErrSessionRunning = fmt.Errorf("%s already running", settings.CmdName)
+)
+
+type Options struct {
+	common.TraceOptions
+	*options.Options
+}
+
+func NewCommand(opts *options.Options) *cobra.Command {
+	o := new(Options)
+	o.Options = opts
+	cmd := &cobra.Command{
+		Use:   fmt.Sprintf("%s -- COMMAND [ARG...]", CmdName),
+		Short: "Run a command while profiling the coverage of a program",
+		Long: fmt.Sprintf(`
+%s runs the coverage profiling for the program, waits for the profiler to be ready, and runs the command, like a test runner.
+When the command exits, the profiling is stopped and the report is written.
+It exits with the exit code of the command, or with code %d when the command succeeds but any coverage threshold is not met.
+`, CmdName, common.ExitCodeThreshold),
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		Args:              cobra.MinimumNArgs(1),
+		RunE:              o.Run,
+	}
+
+	o.TraceOptions.AddFlags(cmd.Flags())
+
+	cmd.MarkFlagRequired("path")
+
+	return cmd
+}
+
+func (o *Options) Run(_ *cobra.Command, args []string) error {
+	if common.IsDaemonRunning(o.PidFile()) {
+		return errors.Wrapf(ErrSessionRunning, "session %s", o.Session)
+	}
+
+	tracer, err := o.NewTracer(o.Options)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(o.RuntimeDir, 0755); err != nil {
+		return errors.Wrap(err, "failed to create runtime directory")
+	}
+
+	// Store PID file, for the session to be listed.
+	if err := os.WriteFile(o.PidFile(), []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		return errors.Wrap(err, "failed to write PID file")
+	}
+	defer os.Remove(o.PidFile())
+
+	ctx, cancel := context.WithCancel(o.Ctx)
+	defer cancel()
+
+	if err := tracer.Init(ctx); err != nil {
+		return errors.Wrapf(err, "failed to init tracer")
+	}
+
+	runErrCh := make(chan error, 1)
+	go func() {
+		runErrCh <- tracer.Run(ctx)
+	}()
+
+	// Wait for the tracer to be ready.
+	select {
+	case <-tracer.Ready():
+	case err := <-runErrCh:
+		if err != nil {
+			return errors.Wrapf(err, "failed to run tracer")
+		}
+		return ErrTracerNotReady
+	}
+
+	cmdErr := o.runCommand(args)
+
+	// Stop the tracer, that writes the report.
+	cancel()
+	runErr := common.ThresholdExitError(<-runErrCh)
+
+	if cmdErr != nil {
+		if runErr != nil {
+			o.Logger.Error().Err(runErr).Msg("failed to run tracer")
+		}
+		return cmdErr
+	}
+	if runErr != nil {
+		return errors.Wrapf(runErr, "failed to run tracer")
+	}
+
+	return nil
+}
+
+// runCommand runs the command and returns an *common.ExitError with the
+// command exit code when it fails.
+func (o *Options) runCommand(args []string) error {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	o.Logger.Info().Strs("command", args).Msg("running command")
+	if err := cmd.Run(); err != nil {
+		var exitErr *common.ExitError
+		if errors.As(common.CommandExitError(err), &exitErr) {
+			o.Logger.Info().Int("exit_code", exitErr.Code).Msg("command failed")
+			return exitErr
+		}
+		return errors.Wrap(err, "failed to run command")
+	}
+
+	return nil
+}
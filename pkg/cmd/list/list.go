@@ -0,0 +1,142 @@
+package list
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/maxgio92/xcover/internal/settings"
+	"github.com/maxgio92/xcover/pkg/client"
+	"github.com/maxgio92/xcover/pkg/cmd/common"
+	"github.com/maxgio92/xcover/pkg/cmd/options"
+	"github.com/maxgio92/xcover/pkg/healthcheck"
+)
+
+const (
+	CmdName = "list"
+
+	OutputText = "text"
+	OutputJSON = "json"
+
+	StateStopped = "stopped"
+	StateRunning = "running"
+	StateReady   = "ready"
+
+	statusTimeout = 5 * time.Second
+)
+
+var ErrOutputUnknown = errors.New("unknown output format")
+
+type Options struct {
+	output string
+	*options.Options
+}
+
+// sessionOutput is the state of a session listed, with the tracing
+// statistics when the profiler is ready.
+type sessionOutput struct {
+	Name    string `json:"name"`
+	State   string `json:"state"`
+	Pid     int    `json:"pid,omitempty"`
+	LogFile string `json:"log_file"`
+	*healthcheck.Status
+}
+
+func NewCommand(opts *options.Options) *cobra.Command {
+	o := &Options{Options: opts}
+	cmd := &cobra.Command{
+		Use:   CmdName,
+		Short: fmt.Sprintf("List the %s profiling sessions", settings.CmdName),
+		Long: fmt.Sprintf(`
+%s lists the %s profiling sessions with a PID file in the runtime directory, and their state:
+stopped, when the profiler is not running anymore, running, or ready, along with the traced executable and the current coverage.
+`, CmdName, settings.CmdName),
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		RunE:              o.Run,
+	}
+
+	cmd.Flags().StringVarP(&o.output, "output", "o", OutputText, fmt.Sprintf("Output format (%s, %s)", OutputText, OutputJSON))
+
+	return cmd
+}
+
+func (o *Options) Run(_ *cobra.Command, _ []string) error {
+	if o.output != OutputText && o.output != OutputJSON {
+		return errors.Wrap(ErrOutputUnknown, o.output)
+	}
+
+	sessions, err := settings.Sessions(o.RuntimeDir)
+	if err != nil {
+		return err
+	}
+
+	out := make([]*sessionOutput, 0, len(sessions))
+	for _, session := range sessions {
+		out = append(out, o.sessionOutput(session))
+	}
+
+	if o.output == OutputJSON {
+		return json.NewEncoder(os.Stdout).Encode(out)
+	}
+
+	return printText(os.Stdout, out)
+}
+
+func (o *Options) sessionOutput(session string) *sessionOutput {
+	out := &sessionOutput{
+		Name:    session,
+		State:   StateStopped,
+		LogFile: settings.LogFile(o.RuntimeDir, session),
+	}
+
+	pidFile := settings.PidFile(o.RuntimeDir, session)
+	if !common.IsDaemonRunning(pidFile) {
+		return out
+	}
+	out.State = StateRunning
+	pidData, _ := os.ReadFile(pidFile)
+	out.Pid, _ = strconv.Atoi(strings.TrimSpace(string(pidData)))
+
+	sockFile := settings.SockFile(o.RuntimeDir, session)
+	status, err := client.New(sockFile, client.WithTimeout(statusTimeout)).Status(o.Ctx)
+	switch {
+	case err == nil:
+		out.State = StateReady
+		out.Status = status
+	case errors.Is(err, healthcheck.ErrNotReady):
+	default:
+		o.Logger.Debug().Err(err).Str("session", session).Msg("failed to get the profiler status")
+	}
+
+	return out
+}
+
+func printText(w io.Writer, out []*sessionOutput) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SESSION\tSTATE\tPID\tEXECUTABLE\tCOVERAGE")
+	for _, s := range out {
+		pid, exe, cov := "-", "-", "-"
+		if s.Pid > 0 {
+			pid = strconv.Itoa(s.Pid)
+		}
+		if s.Status != nil {
+			exe = s.ExePath
+			if len(s.ExePaths) > 1 {
+				exe = strings.Join(s.ExePaths, ",")
+			}
+			cov = fmt.Sprintf("%.2f%%", s.CovByFunc)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", s.Name, s.State, pid, exe, cov)
+	}
+
+	return tw.Flush()
+}
@@ -0,0 +1,54 @@
+package mark
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/maxgio92/xcover/internal/settings"
+	"github.com/maxgio92/xcover/pkg/client"
+	"github.com/maxgio92/xcover/pkg/cmd/options"
+)
+
+const CmdName = "mark"
+
+type Options struct {
+	socketPath string
+	*options.Options
+}
+
+func NewCommand(opts *options.Options) *cobra.Command {
+	o := &Options{Options: opts}
+	cmd := &cobra.Command{
+		Use:   fmt.Sprintf("%s [LABEL]", CmdName),
+		Short: "Mark the label of the functions called from now on, like the name of the test being run",
+		Long: fmt.Sprintf(`
+%s tells the running %s profiler the label of the functions called from now on, like the name of the test being run.
+The report then includes the functions acknowledged for each label.
+Without a label, the functions called from now on are not labeled.
+`, CmdName, settings.CmdName),
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		Args:              cobra.MaximumNArgs(1),
+		RunE:              o.Run,
+	}
+
+	cmd.Flags().StringVarP(&o.socketPath, "socket-path", "s", "", fmt.Sprintf("Path to the %s socket file (default to the one in the runtime directory)", settings.CmdName))
+
+	return cmd
+}
+
+func (o *Options) Run(_ *cobra.Command, args []string) error {
+	var label string
+	if len(args) > 0 {
+		label = args[0]
+	}
+
+	if err := client.New(o.SocketPath(o.socketPath)).Mark(o.Ctx, label); err != nil {
+		return errors.Wrapf(err, "failed to mark label %q", label)
+	}
+	o.Logger.Info().Str("label", label).Msg("label marked")
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package merge
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/maxgio92/xcover/pkg/cmd/options"
+	"github.com/maxgio92/xcover/pkg/coverage"
+	"github.com/maxgio92/xcover/pkg/trace"
+)
+
+const CmdName = "merge"
+
+type Options struct {
+	output string
+	format string
+
+	*options.Options
+}
+
+func NewCommand(opts *options.Options) *cobra.Command {
+	o := new(Options)
+	o.Options = opts
+	cmd := &cobra.Command{
+		Use:   fmt.Sprintf("%s REPORT...", CmdName),
+		Short: "Merge multiple coverage reports of the same executable",
+		Long: fmt.Sprintf(`
+%s merges multiple JSON coverage reports of the same executable into a single report.
+The functions acknowledged are merged and the function hits are summed, if any.
+The reports must refer to the same executable path and build ID.
+`, CmdName),
+		Args:              cobra.MinimumNArgs(1),
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		RunE:              o.Run,
+	}
+
+	cmd.Flags().StringVarP(&o.output, "output", "o", "", "Path to the merged report (default to the report file name for the format)")
+	cmd.Flags().StringVarP(&o.format, "format", "f", string(coverage.ReportFormatJSON), fmt.Sprintf("Merged report format (%s)", strings.Join(coverage.ReportFormatNames(), ", ")))
+
+	return cmd
+}
+
+func (o *Options) Run(_ *cobra.Command, args []string) error {
+	format := coverage.ReportFormat(o.format)
+	if err := format.Validate(); err != nil {
+		return err
+	}
+
+	reports := make([]*coverage.CoverageReport, 0, len(args))
+	for _, path := range args {
+		report, err := coverage.ReadReportFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read report %s", path)
+		}
+		reports = append(reports, report)
+	}
+
+	merged, err := coverage.Merge(reports...)
+	if err != nil {
+		return errors.Wrap(err, "failed to merge reports")
+	}
+
+	output := o.output
+	if output == "" {
+		output = trace.ReportFilePath(format)
+	}
+	out, err := os.Create(output)
+	if err != nil {
+		return errors.Wrap(err, "failed to create report file")
+	}
+	defer out.Close()
+
+	if err := merged.WriteReportFormat(out, format); err != nil {
+		return errors.Wrapf(err, "failed to write %s report", format)
+	}
+	o.Logger.Info().Str("path", output).Int("reports", len(reports)).Msg("reports merged")
+
+	return nil
+}
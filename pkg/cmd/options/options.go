@@ -4,18 +4,28 @@ import (
 	"context"
 
 	log "github.com/rs/zerolog"
+
+	"github.com/maxgio92/xcover/internal/settings"
 )
 
 type Options struct {
 	Ctx      context.Context
 	Logger   log.Logger
 	LogLevel string
+	// RuntimeDir is the directory of the PID file, the socket and the log file.
+	RuntimeDir string
+	// Session is the name of the profiling session, that namespaces its
+	// PID file, socket, log file and report.
+	Session string
 }
 
 type Option func(o *Options)
 
 func NewOptions(opts ...Option) *Options {
-	o := new(Options)
+	o := &Options{
+		RuntimeDir: settings.DefaultRuntimeDir,
+		Session:    settings.DefaultSession,
+	}
 
 	for _, f := range opts {
 		f(o)
@@ -41,3 +51,35 @@ func WithLogLevel(level string) Option {
 		o.LogLevel = level
 	}
 }
+
+func WithRuntimeDir(dir string) Option {
+	return func(o *Options) {
+		o.RuntimeDir = dir
+	}
+}
+
+func WithSession(session string) Option {
+	return func(o *Options) {
+		o.Session = session
+	}
+}
+
+// PidFile returns the path of the PID file of the session.
+func (o *Options) PidFile() string {
+	return settings.PidFile(o.RuntimeDir, o.Session)
+}
+
+// LogFile returns the path of the log file of the session.
+func (o *Options) LogFile() string {
+	return settings.LogFile(o.RuntimeDir, o.Session)
+}
+
+// SocketPath returns the path, when not empty, or the path of the socket
+// file of the session.
+func (o *Options) SocketPath(path string) string {
+	if path != "" {
+		return path
+	}
+
+	return settings.SockFile(o.RuntimeDir, o.Session)
+}
@@ -0,0 +1,75 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/maxgio92/xcover/pkg/cmd/common"
+	"github.com/maxgio92/xcover/pkg/cmd/options"
+	"github.com/maxgio92/xcover/pkg/coverage"
+	"github.com/maxgio92/xcover/pkg/trace"
+)
+
+const CmdName = "report"
+
+type Options struct {
+	input  string
+	output string
+	format string
+
+	*options.Options
+}
+
+func NewCommand(opts *options.Options) *cobra.Command {
+	o := new(Options)
+	o.Options = opts
+	cmd := &cobra.Command{
+		Use:   CmdName,
+		Short: "Generate a coverage report from an existing JSON report",
+		Long: fmt.Sprintf(`
+%s generates a coverage report in the specified format from an existing report in JSON format.
+`, CmdName),
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		RunE:              o.Run,
+	}
+
+	cmd.Flags().StringVarP(&o.input, "input", "i", "", "Path to the JSON report (default to the one of the session)")
+	cmd.Flags().StringVarP(&o.output, "output", "o", "", "Path to the generated report (default to the report file name for the format)")
+	cmd.Flags().StringVarP(&o.format, "format", "f", string(coverage.ReportFormatHTML), fmt.Sprintf("Report format (%s)", strings.Join(coverage.ReportFormatNames(), ", ")))
+
+	return cmd
+}
+
+func (o *Options) Run(_ *cobra.Command, _ []string) error {
+	format := coverage.ReportFormat(o.format)
+	if err := format.Validate(); err != nil {
+		return err
+	}
+
+	report, err := coverage.ReadReportFile(common.ReportPath(o.input, o.Session))
+	if err != nil {
+		return err
+	}
+
+	output := o.output
+	if output == "" {
+		output = trace.ReportFilePath(format)
+	}
+	out, err := os.Create(output)
+	if err != nil {
+		return errors.Wrap(err, "failed to create report file")
+	}
+	defer out.Close()
+
+	if err := report.WriteReportFormat(out, format); err != nil {
+		return errors.Wrapf(err, "failed to write %s report", format)
+	}
+	o.Logger.Info().Str("path", output).Str("format", string(format)).Msg("report generated")
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package reset
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/maxgio92/xcover/internal/settings"
+	"github.com/maxgio92/xcover/pkg/client"
+	"github.com/maxgio92/xcover/pkg/cmd/options"
+	"github.com/maxgio92/xcover/pkg/coverage"
+)
+
+const CmdName = "reset"
+
+type Options struct {
+	socketPath   string
+	reportPath   string
+	reportFormat string
+	*options.Options
+}
+
+func NewCommand(opts *options.Options) *cobra.Command {
+	o := &Options{Options: opts}
+	cmd := &cobra.Command{
+		Use:   CmdName,
+		Short: "Reset the coverage while profiling",
+		Long: fmt.Sprintf(`
+%s asks the running %s profiler to reset the coverage, so that it starts from zero, without attaching the functions again.
+Optionally, the coverage report before the reset is written.
+`, CmdName, settings.CmdName),
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		Args:              cobra.NoArgs,
+		RunE:              o.Run,
+	}
+
+	cmd.Flags().StringVarP(&o.socketPath, "socket-path", "s", "", fmt.Sprintf("Path to the %s socket file (default to the one in the runtime directory)", settings.CmdName))
+	cmd.Flags().StringVar(&o.reportPath, "report-path", "", "Path to write the coverage report before the reset to")
+	cmd.Flags().StringVar(&o.reportFormat, "report-format", string(coverage.ReportFormatJSON), fmt.Sprintf("Report format (%s)", strings.Join(coverage.ReportFormatNames(), ", ")))
+
+	return cmd
+}
+
+func (o *Options) Run(_ *cobra.Command, _ []string) error {
+	format := coverage.ReportFormat(o.reportFormat)
+	if err := format.Validate(); err != nil {
+		return err
+	}
+
+	var path string
+	if o.reportPath != "" {
+		// The profiler can run from another working directory.
+		var err error
+		path, err = filepath.Abs(o.reportPath)
+		if err != nil {
+			return errors.Wrap(err, "invalid report path")
+		}
+	}
+
+	if err := client.New(o.SocketPath(o.socketPath)).Reset(o.Ctx, format, path); err != nil {
+		return errors.Wrap(err, "failed to reset the coverage")
+	}
+	o.Logger.Info().Msg("coverage reset")
+
+	return nil
+}
@@ -14,7 +14,6 @@ import (
 	"github.com/maxgio92/xcover/internal/settings"
 	"github.com/maxgio92/xcover/pkg/cmd/common"
 	"github.com/maxgio92/xcover/pkg/cmd/options"
-	"github.com/maxgio92/xcover/pkg/trace"
 )
 
 const (
@@ -27,17 +26,9 @@ type FuncName struct {
 }
 
 type Options struct {
-	comm string
-	pid  int
-
-	symExcludePattern string
-	symIncludePattern string
-
-	detach  bool
-	verbose bool
-	report  bool
-	status  bool
+	detach bool
 
+	common.TraceOptions
 	*options.Options
 }
 
@@ -45,40 +36,48 @@ func NewCommand(opts *options.Options) *cobra.Command {
 	o := new(Options)
 	o.Options = opts
 	cmd := &cobra.Command{
-		Use:   CmdName,
+		Use:   fmt.Sprintf("%s [-- ARG...]", CmdName),
 		Short: "Run the coverage profiling for a program",
 		Long: fmt.Sprintf(`
 %s runs the coverage profiling for functional tests by tracing all the functions supported by the program being tested.
-It supports programs compiled to ELF.
-`, CmdName),
+It supports programs compiled to ELF, and more programs can be traced together, like cooperating ones, by repeating --path.
+When arguments are passed after --, %s starts the first program with them and traces only its process tree, since its first instruction.
+When the program exits, the profiling is stopped and %s exits with the exit code of the program.
+`, CmdName, settings.CmdName, settings.CmdName),
 		DisableAutoGenTag: true,
+		SilenceUsage:      true,
 		RunE:              o.Run,
 	}
 
-	cmd.Flags().StringVarP(&o.comm, "path", "p", "", "Path to the ELF executable")
-	cmd.Flags().IntVar(&o.pid, "pid", -1, "Filter the process by PID")
-
-	cmd.Flags().StringVar(&o.symExcludePattern, "exclude", "", "Regex pattern to exclude function symbol names")
-	cmd.Flags().StringVar(&o.symIncludePattern, "include", "", "Regex pattern to include function symbol names")
-
+	o.TraceOptions.AddFlags(cmd.Flags())
 	cmd.Flags().BoolVarP(&o.detach, "detach", "d", false, fmt.Sprintf("Run %s as daemon", settings.CmdName))
-	cmd.Flags().BoolVar(&o.verbose, "verbose", false, "Enable verbosity")
-	cmd.Flags().BoolVar(&o.report, "report", true, fmt.Sprintf("Generate report (as %s)", trace.ReportFileName))
-	cmd.Flags().BoolVar(&o.status, "status", true, "Periodically print a status of the trace")
 
 	cmd.MarkFlagRequired("path")
 
 	return cmd
 }
 
-func (o *Options) Run(cmd *cobra.Command, _ []string) error {
+func (o *Options) Run(cmd *cobra.Command, args []string) error {
+	switch dash := cmd.ArgsLenAtDash(); {
+	case dash > 0 || (dash < 0 && len(args) > 0):
+		return errors.Errorf("unexpected arguments %v, the program arguments must follow --", args)
+	case dash == 0:
+		o.SetLaunchArgs(args)
+	}
+
 	if o.detach {
 		return o.daemonize()
 	}
 
+	if err := os.MkdirAll(o.RuntimeDir, 0755); err != nil {
+		return errors.Wrap(err, "failed to create runtime directory")
+	}
+
 	// Store PID file.
-	os.WriteFile(settings.PidFile, []byte(strconv.Itoa(os.Getpid())), 0644)
-	defer os.Remove(settings.PidFile)
+	if err := os.WriteFile(o.PidFile(), []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		return errors.Wrap(err, "failed to write PID file")
+	}
+	defer os.Remove(o.PidFile())
 
 	var err error
 	o.LogLevel, err = cmd.Flags().GetString("log-level")
@@ -92,26 +91,16 @@ func (o *Options) Run(cmd *cobra.Command, _ []string) error {
 	}
 	o.Logger = o.Logger.Level(logLevel)
 
-	tracee := trace.NewUserTracee(
-		trace.WithTraceeExePath(o.comm),
-		trace.WithTraceeSymPatternInclude(o.symIncludePattern),
-		trace.WithTraceeSymPatternExclude(o.symExcludePattern),
-		trace.WithTraceeLogger(o.Logger),
-	)
-
-	tracer := trace.NewUserTracer(
-		trace.WithTracerLogger(o.Logger),
-		trace.WithTracerVerbose(o.verbose),
-		trace.WithTracerReport(o.report),
-		trace.WithTracerStatus(o.status),
-		trace.WithTracerTracee(tracee),
-	)
+	tracer, err := o.NewTracer(o.Options)
+	if err != nil {
+		return err
+	}
 
 	if err := tracer.Init(o.Ctx); err != nil {
 		return errors.Wrapf(err, "failed to init tracer")
 	}
 	if err := tracer.Run(o.Ctx); err != nil {
-		return errors.Wrapf(err, "failed to run tracer")
+		return common.ThresholdExitError(common.CommandExitError(errors.Wrapf(err, "failed to run tracer")))
 	}
 
 	return nil
@@ -119,42 +108,41 @@ func (o *Options) Run(cmd *cobra.Command, _ []string) error {
 
 func (o *Options) daemonize() error {
 	// Check if already running.
-	if common.IsDaemonRunning() {
-		fmt.Println("Daemon already running")
+	if common.IsDaemonRunning(o.PidFile()) {
+		fmt.Printf("Daemon already running in session %s\n", o.Session)
 		return nil
 	}
 
 	// Start the daemon process.
-	args := []string{"run"}
-	args = append(args, fmt.Sprintf("--path=%s", o.comm))
-	args = append(args, fmt.Sprintf("--exclude=%s", o.symExcludePattern))
-	args = append(args, fmt.Sprintf("--include=%s", o.symIncludePattern))
-	args = append(args, fmt.Sprintf("--report=%s", strconv.FormatBool(o.report)))
-	args = append(args, fmt.Sprintf("--status=%s", strconv.FormatBool(o.status)))
-	args = append(args, fmt.Sprintf("--verbose=%s", strconv.FormatBool(o.verbose)))
+	args := []string{"run", fmt.Sprintf("--runtime-dir=%s", o.RuntimeDir), fmt.Sprintf("--session=%s", o.Session)}
+	args = append(args, o.TraceOptions.Args()...)
 
 	cmd := exec.Command(os.Args[0], args...)
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
 
+	if err := os.MkdirAll(o.RuntimeDir, 0755); err != nil {
+		o.Logger.Error().Err(err).Msg("failed to create runtime directory")
+		return err
+	}
+
 	// Redirect output to log file.
-	if settings.LogFile != "" {
-		f, err := os.OpenFile(settings.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-		if err != nil {
-			o.Logger.Error().Err(err).Msg("failed to open log file")
-			return err
-		}
-		cmd.Stdout = f
-		cmd.Stderr = f
+	f, err := os.OpenFile(o.LogFile(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		o.Logger.Error().Err(err).Msg("failed to open log file")
+		return err
 	}
+	defer f.Close()
+	cmd.Stdout = f
+	cmd.Stderr = f
 
-	err := cmd.Start()
+	err = cmd.Start()
 	if err != nil {
 		o.Logger.Error().Err(err).Msgf("failed to start %s", settings.CmdName)
 		return err
 	}
 
 	// Store PID file.
-	err = os.WriteFile(settings.PidFile, []byte(strconv.Itoa(cmd.Process.Pid)), 0644)
+	err = os.WriteFile(o.PidFile(), []byte(strconv.Itoa(cmd.Process.Pid)), 0644)
 	if err != nil {
 		o.Logger.Error().Err(err).Msg("failed to write PID file")
 		return err
@@ -0,0 +1,63 @@
+package snapshot
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/maxgio92/xcover/internal/settings"
+	"github.com/maxgio92/xcover/pkg/client"
+	"github.com/maxgio92/xcover/pkg/cmd/options"
+	"github.com/maxgio92/xcover/pkg/coverage"
+)
+
+const CmdName = "snapshot"
+
+type Options struct {
+	socketPath   string
+	reportFormat string
+	*options.Options
+}
+
+func NewCommand(opts *options.Options) *cobra.Command {
+	o := &Options{Options: opts}
+	cmd := &cobra.Command{
+		Use:   fmt.Sprintf("%s REPORT_PATH", CmdName),
+		Short: "Write the current coverage report while profiling",
+		Long: fmt.Sprintf(`
+%s asks the running %s profiler to write the current coverage report to the path, while it keeps tracing.
+`, CmdName, settings.CmdName),
+		DisableAutoGenTag: true,
+		SilenceUsage:      true,
+		Args:              cobra.ExactArgs(1),
+		RunE:              o.Run,
+	}
+
+	cmd.Flags().StringVarP(&o.socketPath, "socket-path", "s", "", fmt.Sprintf("Path to the %s socket file (default to the one in the runtime directory)", settings.CmdName))
+	cmd.Flags().StringVar(&o.reportFormat, "report-format", string(coverage.ReportFormatJSON), fmt.Sprintf("Report format (%s)", strings.Join(coverage.ReportFormatNames(), ", ")))
+
+	return cmd
+}
+
+func (o *Options) Run(_ *cobra.Command, args []string) error {
+	format := coverage.ReportFormat(o.reportFormat)
+	if err := format.Validate(); err != nil {
+		return err
+	}
+
+	// The profiler can run from another working directory.
+	path, err := filepath.Abs(args[0])
+	if err != nil {
+		return errors.Wrap(err, "invalid report path")
+	}
+
+	if err := client.New(o.SocketPath(o.socketPath)).Snapshot(o.Ctx, format, path); err != nil {
+		return errors.Wrap(err, "failed to snapshot the report")
+	}
+	o.Logger.Info().Str("path", path).Str("format", string(format)).Msg("report snapshot written")
+
+	return nil
+}
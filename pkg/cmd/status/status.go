@@ -1,38 +1,121 @@
 package status
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"github.com/maxgio92/xcover/internal/settings"
+	"github.com/maxgio92/xcover/pkg/client"
 	"github.com/maxgio92/xcover/pkg/cmd/common"
 	"github.com/maxgio92/xcover/pkg/cmd/options"
+	"github.com/maxgio92/xcover/pkg/healthcheck"
 )
 
+const (
+	CmdName = "status"
+
+	OutputText = "text"
+	OutputJSON = "json"
+
+	statusTimeout = 5 * time.Second
+)
+
+var ErrOutputUnknown = errors.New("unknown output format")
+
 type Options struct {
+	socketPath string
+	output     string
 	*options.Options
 }
 
+// statusOutput is the status printed, with the tracing statistics when
+// the profiler is ready.
+type statusOutput struct {
+	Running bool `json:"running"`
+	Ready   bool `json:"ready"`
+	*healthcheck.Status
+}
+
 func NewCommand(opts *options.Options) *cobra.Command {
-	o := &Options{opts}
+	o := &Options{Options: opts}
 	cmd := &cobra.Command{
-		Use:               "status",
-		Short:             fmt.Sprintf("Check the the %s profiler status", settings.CmdName),
+		Use:   CmdName,
+		Short: fmt.Sprintf("Check the the %s profiler status", settings.CmdName),
+		Long: fmt.Sprintf(`
+%s checks whether the %s profiler is running and, when it is ready, prints the tracing statistics:
+the traced executable, the functions attached and failed to attach, the current coverage,
+the events consumed, the events ring buffer utilization and the uptime.
+`, CmdName, settings.CmdName),
 		DisableAutoGenTag: true,
 		SilenceUsage:      true,
-		Run:               o.Run,
+		RunE:              o.Run,
 	}
 
+	cmd.Flags().StringVarP(&o.socketPath, "socket-path", "s", "", fmt.Sprintf("Path to the %s socket file (default to the one in the runtime directory)", settings.CmdName))
+	cmd.Flags().StringVarP(&o.output, "output", "o", OutputText, fmt.Sprintf("Output format (%s, %s)", OutputText, OutputJSON))
+
 	return cmd
 }
 
-func (o *Options) Run(cmd *cobra.Command, _ []string) {
-	if common.IsDaemonRunning() {
-		pidData, _ := os.ReadFile(settings.PidFile)
-		fmt.Printf("%s is running (PID %s)\n", settings.CmdName, pidData)
+func (o *Options) Run(_ *cobra.Command, _ []string) error {
+	if o.output != OutputText && o.output != OutputJSON {
+		return errors.Wrap(ErrOutputUnknown, o.output)
+	}
+
+	out := new(statusOutput)
+	if common.IsDaemonRunning(o.PidFile()) {
+		out.Running = true
+
+		status, err := client.New(o.SocketPath(o.socketPath), client.WithTimeout(statusTimeout)).Status(o.Ctx)
+		switch {
+		case err == nil:
+			out.Ready = true
+			out.Status = status
+		case errors.Is(err, healthcheck.ErrNotReady):
+		default:
+			o.Logger.Debug().Err(err).Msg("failed to get the profiler status")
+		}
+	}
+
+	if o.output == OutputJSON {
+		return json.NewEncoder(os.Stdout).Encode(out)
+	}
+
+	return o.printText(os.Stdout, out)
+}
+
+func (o *Options) printText(w io.Writer, out *statusOutput) error {
+	if !out.Running {
+		fmt.Fprintf(w, "%s is not running\n", settings.CmdName)
+		return nil
+	}
+
+	pidData, _ := os.ReadFile(o.PidFile())
+	pid := strings.TrimSpace(string(pidData))
+	if !out.Ready {
+		fmt.Fprintf(w, "%s is running (PID %s), not ready\n", settings.CmdName, pid)
+		return nil
+	}
+
+	s := out.Status
+	fmt.Fprintf(w, "%s is running (PID %s)\n", settings.CmdName, pid)
+	if len(s.ExePaths) > 1 {
+		fmt.Fprintf(w, "Executables:      %s\n", strings.Join(s.ExePaths, ", "))
 	} else {
-		fmt.Printf("%s is not running\n", settings.CmdName)
+		fmt.Fprintf(w, "Executable:       %s\n", s.ExePath)
 	}
+	fmt.Fprintf(w, "Functions:        %d attached, %d failed, %d traced\n", s.FuncsAttached, s.FuncsFailed, s.FuncsTraced)
+	fmt.Fprintf(w, "Coverage:         %.2f%% (%d/%d functions)\n", s.CovByFunc, s.FuncsAck, s.FuncsTraced)
+	fmt.Fprintf(w, "Events consumed:  %d\n", s.EventsConsumed)
+	fmt.Fprintf(w, "Ring buffer:      %.2f%% used\n", s.EventBufUtil)
+	fmt.Fprintf(w, "Uptime:           %s\n", s.Uptime.Round(time.Second))
+
+	return nil
 }
@@ -11,8 +11,10 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/maxgio92/xcover/internal/settings"
+	"github.com/maxgio92/xcover/pkg/client"
 	"github.com/maxgio92/xcover/pkg/cmd/common"
 	"github.com/maxgio92/xcover/pkg/cmd/options"
+	"github.com/maxgio92/xcover/pkg/coverage"
 )
 
 var (
@@ -23,11 +25,14 @@ var (
 )
 
 type Options struct {
+	socketPath string
+
+	common.ThresholdOptions
 	*options.Options
 }
 
 func NewCommand(opts *options.Options) *cobra.Command {
-	o := &Options{opts}
+	o := &Options{Options: opts}
 
 	cmd := &cobra.Command{
 		Use:               "stop",
@@ -37,11 +42,43 @@ func NewCommand(opts *options.Options) *cobra.Command {
 		RunE:              o.Run,
 	}
 
+	cmd.Flags().StringVarP(&o.socketPath, "socket-path", "s", "", fmt.Sprintf("Path to the %s socket file, to get the coverage to check against the thresholds (default to the one in the runtime directory)", settings.CmdName))
+	o.ThresholdOptions.AddFlags(cmd.Flags())
+
 	return cmd
 }
 
 func (o *Options) Run(cmd *cobra.Command, _ []string) error {
-	pidData, err := os.ReadFile(settings.PidFile)
+	thresholds, err := o.Thresholds()
+	if err != nil {
+		return err
+	}
+
+	// Get the coverage before stopping the profiler, that serves it.
+	var report *coverage.CoverageReport
+	if len(thresholds) > 0 {
+		if !common.IsDaemonRunning(o.PidFile()) {
+			return ErrNotRunningOrNotFound
+		}
+		report, err = client.New(o.SocketPath(o.socketPath)).Coverage(o.Ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get the coverage: %w", err)
+		}
+	}
+
+	if err := o.stop(); err != nil {
+		return err
+	}
+
+	if report == nil {
+		return nil
+	}
+
+	return common.CheckThresholds(report, thresholds)
+}
+
+func (o *Options) stop() error {
+	pidData, err := os.ReadFile(o.PidFile())
 	if err != nil {
 		return ErrNotRunningOrNotFound
 	}
@@ -63,9 +100,9 @@ func (o *Options) Run(cmd *cobra.Command, _ []string) error {
 
 	// Wait for process to stop.
 	for i := 0; i < 50; i++ {
-		if !common.IsDaemonRunning() {
+		if !common.IsDaemonRunning(o.PidFile()) {
 			fmt.Printf("%s stopped (PID %d)\n", settings.CmdName, pid)
-			os.Remove(settings.PidFile)
+			os.Remove(o.PidFile())
 
 			return nil
 		}
@@ -74,7 +111,7 @@ func (o *Options) Run(cmd *cobra.Command, _ []string) error {
 
 	// Force kill if still running.
 	process.Kill()
-	os.Remove(settings.PidFile)
+	os.Remove(o.PidFile())
 	fmt.Printf("%s force killed (PID %d)\n", settings.CmdName, pid)
 
 	return nil
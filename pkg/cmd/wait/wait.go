@@ -1,22 +1,20 @@
 package wait
 
 import (
+	"context"
 	"fmt"
-	"net"
-	"os"
 	"syscall"
 	"time"
 
+	"github.com/maxgio92/xcover/pkg/client"
 	"github.com/maxgio92/xcover/pkg/cmd/common"
 	"github.com/maxgio92/xcover/pkg/cmd/options"
-	"github.com/maxgio92/xcover/pkg/healthcheck"
 	log "github.com/rs/zerolog"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"github.com/maxgio92/xcover/internal/settings"
-	"github.com/maxgio92/xcover/pkg/trace"
 )
 
 const CmdName = "wait"
@@ -42,7 +40,7 @@ func NewCommand(opts *options.Options) *cobra.Command {
 		RunE:              o.Run,
 	}
 
-	cmd.Flags().StringVarP(&o.socketPath, "socket-path", "s", trace.HealthCheckSockPath, fmt.Sprintf("Path to the %s socket file", settings.CmdName))
+	cmd.Flags().StringVarP(&o.socketPath, "socket-path", "s", "", fmt.Sprintf("Path to the %s socket file (default to the one in the runtime directory)", settings.CmdName))
 	cmd.Flags().DurationVar(&o.timeout, "timeout", time.Second*120, "Timeout")
 
 	return cmd
@@ -61,61 +59,34 @@ func (o *Options) Run(cmd *cobra.Command, _ []string) error {
 	}
 	o.Logger = o.Logger.Level(logLevel).With().Str("component", "wait").Logger()
 
-	if !common.IsDaemonRunning() {
+	if !common.IsDaemonRunning(o.PidFile()) {
 		return ErrNotRunning
 	}
 
-	start := time.Now()
+	ctx, cancel := context.WithTimeout(o.Ctx, o.timeout)
+	defer cancel()
+
 	retryInterval := 500 * time.Millisecond
 	o.Logger.Info().Msg("waiting for the profiler to be ready")
 
+	c := client.New(o.SocketPath(o.socketPath))
 	for {
-		if time.Since(start) >= o.timeout {
-			return errors.New("timeout waiting for profiler readiness")
-		}
-
-		// Check if socket exists.
-		info, err := os.Stat(o.socketPath)
-		if err != nil {
-			if os.IsNotExist(err) {
-				time.Sleep(retryInterval)
-				continue
-			}
-			return fmt.Errorf("error checking socket: %w", err)
-		}
-
-		if info.Mode()&os.ModeSocket == 0 {
-			return fmt.Errorf("path exists but is not a Unix socket: %s", o.socketPath)
-		}
-
-		// Try to connect.
-		conn, err := net.DialTimeout("unix", o.socketPath, retryInterval)
-		if err != nil {
-			if errors.Is(err, syscall.EACCES) {
-				return errors.Wrap(err, "failed connecting")
-			}
-			time.Sleep(retryInterval)
-			continue
-		}
-
-		defer conn.Close()
-
-		// Try reading one byte.
-		buf := make([]byte, 1)
-		conn.SetReadDeadline(time.Now().Add(retryInterval))
-
-		n, err := conn.Read(buf)
-		if err != nil || n == 0 {
-			time.Sleep(retryInterval)
-			continue
-		}
-
-		if buf[0] == healthcheck.ReadyMsg {
+		// The socket is created while the profiler starts.
+		err := c.Ready(ctx)
+		if err == nil {
 			o.Logger.Info().Msg("profiler is ready")
 			fmt.Printf("%s is ready\n", settings.CmdName)
 			return nil
 		}
+		if errors.Is(err, syscall.EACCES) {
+			return errors.Wrap(err, "failed connecting")
+		}
+		o.Logger.Debug().Err(err).Msg("profiler is not ready")
 
-		time.Sleep(retryInterval)
+		select {
+		case <-ctx.Done():
+			return errors.New("timeout waiting for profiler readiness")
+		case <-time.After(retryInterval):
+		}
 	}
 }
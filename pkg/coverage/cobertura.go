@@ -0,0 +1,194 @@
+package coverage
+
+import (
+	"encoding/xml"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/maxgio92/xcover/internal/settings"
+)
+
+const coberturaDocType = `<!DOCTYPE coverage SYSTEM "http://cobertura.sourceforge.net/xml/coverage-04.dtd">`
+
+type coberturaCoverage struct {
+	XMLName         xml.Name           `xml:"coverage"`
+	LineRate        float64            `xml:"line-rate,attr"`
+	BranchRate      float64            `xml:"branch-rate,attr"`
+	LinesCovered    int                `xml:"lines-covered,attr"`
+	LinesValid      int                `xml:"lines-valid,attr"`
+	BranchesCovered int                `xml:"branches-covered,attr"`
+	BranchesValid   int                `xml:"branches-valid,attr"`
+	Complexity      float64            `xml:"complexity,attr"`
+	Version         string             `xml:"version,attr"`
+	Timestamp       int64              `xml:"timestamp,attr"`
+	Packages        []coberturaPackage `xml:"packages>package"`
+}
+
+type coberturaPackage struct {
+	Name       string           `xml:"name,attr"`
+	LineRate   float64          `xml:"line-rate,attr"`
+	BranchRate float64          `xml:"branch-rate,attr"`
+	Complexity float64          `xml:"complexity,attr"`
+	Classes    []coberturaClass `xml:"classes>class"`
+}
+
+type coberturaClass struct {
+	Name       string            `xml:"name,attr"`
+	Filename   string            `xml:"filename,attr"`
+	LineRate   float64           `xml:"line-rate,attr"`
+	BranchRate float64           `xml:"branch-rate,attr"`
+	Complexity float64           `xml:"complexity,attr"`
+	Methods    []coberturaMethod `xml:"methods>method"`
+	Lines      []coberturaLine   `xml:"lines>line"`
+}
+
+type coberturaMethod struct {
+	Name       string          `xml:"name,attr"`
+	Signature  string          `xml:"signature,attr"`
+	LineRate   float64         `xml:"line-rate,attr"`
+	BranchRate float64         `xml:"branch-rate,attr"`
+	Complexity float64         `xml:"complexity,attr"`
+	Lines      []coberturaLine `xml:"lines>line"`
+}
+
+type coberturaLine struct {
+	Number int    `xml:"number,attr"`
+	Hits   uint64 `xml:"hits,attr"`
+}
+
+// WriteCobertura writes the report in the Cobertura XML format.
+// Functions are reported as methods, each one with a single line, that is the
+// line where the function is declared, if resolved from the DWARF debug info.
+// Methods are grouped into packages and classes by the Go package path, the
+// C++ namespace or the directory of the source file, and by the source file
+// or the enclosing type, respectively.
+func (r *CoverageReport) WriteCobertura(w io.Writer) error {
+	funcs := r.funcs()
+
+	type classKey struct {
+		name     string
+		filename string
+	}
+	groups := make(map[string]map[classKey][]reportFunc)
+
+	for _, fn := range funcs {
+		pkg, class := funcScope(fn)
+		if groups[pkg] == nil {
+			groups[pkg] = make(map[classKey][]reportFunc)
+		}
+		key := classKey{name: class, filename: fn.file}
+		groups[pkg][key] = append(groups[pkg][key], fn)
+	}
+
+	doc := coberturaCoverage{
+		Version:   settings.Version(),
+		Timestamp: time.Now().Unix(),
+	}
+	for pkgName, classes := range groups {
+		pkg := coberturaPackage{Name: pkgName}
+		var pkgValid, pkgCovered int
+
+		for key, methods := range classes {
+			sort.Slice(methods, func(i, j int) bool {
+				if methods[i].line != methods[j].line {
+					return methods[i].line < methods[j].line
+				}
+				return methods[i].name < methods[j].name
+			})
+
+			class := coberturaClass{Name: key.name, Filename: key.filename}
+			var covered int
+			for _, fn := range methods {
+				line := coberturaLine{Number: fn.line, Hits: fn.hits}
+				method := coberturaMethod{
+					Name:  fn.name,
+					Lines: []coberturaLine{line},
+				}
+				if fn.hits > 0 {
+					method.LineRate = 1
+					covered++
+				}
+				class.Methods = append(class.Methods, method)
+				class.Lines = append(class.Lines, line)
+			}
+			class.LineRate = rate(covered, len(methods))
+			pkg.Classes = append(pkg.Classes, class)
+
+			pkgValid += len(methods)
+			pkgCovered += covered
+		}
+		sort.Slice(pkg.Classes, func(i, j int) bool {
+			if pkg.Classes[i].Name != pkg.Classes[j].Name {
+				return pkg.Classes[i].Name < pkg.Classes[j].Name
+			}
+			return pkg.Classes[i].Filename < pkg.Classes[j].Filename
+		})
+		pkg.LineRate = rate(pkgCovered, pkgValid)
+		doc.Packages = append(doc.Packages, pkg)
+
+		doc.LinesValid += pkgValid
+		doc.LinesCovered += pkgCovered
+	}
+	sort.Slice(doc.Packages, func(i, j int) bool {
+		return doc.Packages[i].Name < doc.Packages[j].Name
+	})
+	doc.LineRate = rate(doc.LinesCovered, doc.LinesValid)
+
+	if _, err := io.WriteString(w, xml.Header+coberturaDocType+"\n"); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+
+	return err
+}
+
+// funcScope returns the package and the class a function belongs to.
+func funcScope(fn reportFunc) (pkg, class string) {
+	var scope string
+	switch {
+	case strings.HasSuffix(fn.file, ".go") || fn.goExe:
+		pkg, scope = goFuncScope(fn.name)
+	default:
+		if names := cxxFuncScope(fn.name); len(names) > 0 {
+			scope = strings.Join(names, "::")
+			pkg = scope
+			if len(names) > 1 {
+				pkg = strings.Join(names[:len(names)-1], "::")
+			}
+		}
+	}
+
+	if pkg == "" && fn.file != "" {
+		pkg = filepath.Dir(fn.file)
+	}
+	if pkg == "" {
+		pkg = fn.exePath
+	}
+
+	switch {
+	case fn.file != "":
+		class = filepath.Base(fn.file)
+	case scope != "":
+		class = scope
+	default:
+		class = pkg
+	}
+
+	return pkg, class
+}
+
+func rate(covered, valid int) float64 {
+	if valid == 0 {
+		return 0
+	}
+
+	return float64(covered) / float64(valid)
+}
@@ -0,0 +1,88 @@
+package coverage_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxgio92/xcover/pkg/coverage"
+)
+
+var testBinary = path.Join("..", "trace", "testdata", "gotest")
+
+type testCobertura struct {
+	LineRate float64 `xml:"line-rate,attr"`
+	Packages []struct {
+		Name     string  `xml:"name,attr"`
+		LineRate float64 `xml:"line-rate,attr"`
+		Classes  []struct {
+			Name     string `xml:"name,attr"`
+			Filename string `xml:"filename,attr"`
+			Methods  []struct {
+				Name     string  `xml:"name,attr"`
+				LineRate float64 `xml:"line-rate,attr"`
+				Lines    []struct {
+					Number int    `xml:"number,attr"`
+					Hits   uint64 `xml:"hits,attr"`
+				} `xml:"lines>line"`
+			} `xml:"methods>method"`
+		} `xml:"classes>class"`
+	} `xml:"packages>package"`
+}
+
+func TestWriteCobertura_Go(t *testing.T) {
+	report := coverage.NewCoverageReport(
+		coverage.WithReportFuncsTraced([]string{"main.fooFunction", "main.barFunction"}),
+		coverage.WithReportFuncsAck([]string{"main.fooFunction"}),
+		coverage.WithReportFuncsHits(map[string]uint64{"main.fooFunction": 7}),
+		coverage.WithReportExePath(testBinary),
+	)
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteCobertura(&buf))
+	require.Contains(t, buf.String(), "<!DOCTYPE coverage")
+
+	var doc testCobertura
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+	require.Equal(t, 0.5, doc.LineRate)
+	require.Len(t, doc.Packages, 1)
+	require.Equal(t, "main", doc.Packages[0].Name)
+	require.Len(t, doc.Packages[0].Classes, 1)
+
+	class := doc.Packages[0].Classes[0]
+	require.Equal(t, "test.go", class.Name)
+	require.Equal(t, "test.go", path.Base(class.Filename))
+	require.Len(t, class.Methods, 2)
+	require.Equal(t, "main.fooFunction", class.Methods[0].Name)
+	require.Equal(t, 1.0, class.Methods[0].LineRate)
+	require.Equal(t, 9, class.Methods[0].Lines[0].Number)
+	require.Equal(t, uint64(7), class.Methods[0].Lines[0].Hits)
+	require.Equal(t, "main.barFunction", class.Methods[1].Name)
+	require.Equal(t, 0.0, class.Methods[1].LineRate)
+}
+
+func TestWriteCobertura_CXX(t *testing.T) {
+	report := coverage.NewCoverageReport(
+		coverage.WithReportFuncsTraced([]string{"_ZN3foo3Bar3runEv", "_ZN3foo3BarC2Ev", "main"}),
+		coverage.WithReportFuncsAck([]string{"_ZN3foo3Bar3runEv", "main"}),
+		coverage.WithReportExePath("mybin"),
+	)
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteCobertura(&buf))
+
+	var doc testCobertura
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+	require.Len(t, doc.Packages, 2)
+
+	require.Equal(t, "foo", doc.Packages[0].Name)
+	require.Equal(t, 0.5, doc.Packages[0].LineRate)
+	require.Equal(t, "foo::Bar", doc.Packages[0].Classes[0].Name)
+	require.Len(t, doc.Packages[0].Classes[0].Methods, 2)
+
+	require.Equal(t, "mybin", doc.Packages[1].Name)
+	require.Equal(t, 1.0, doc.Packages[1].LineRate)
+}
@@ -0,0 +1,114 @@
+package coverage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ReportDiff is the difference between two coverage reports.
+type ReportDiff struct {
+	// NewlyCovered are the functions covered only in the head report.
+	NewlyCovered []string `json:"newly_covered"`
+	// NewlyUncovered are the functions covered only in the base report.
+	NewlyUncovered []string `json:"newly_uncovered"`
+	// FuncsAdded are the functions traced only in the head report.
+	FuncsAdded []string `json:"funcs_added"`
+	// FuncsRemoved are the functions traced only in the base report.
+	FuncsRemoved []string `json:"funcs_removed"`
+
+	CovByFuncBase  float64 `json:"cov_by_func_base"`
+	CovByFuncHead  float64 `json:"cov_by_func_head"`
+	CovByFuncDelta float64 `json:"cov_by_func_delta"`
+}
+
+// Diff returns the difference of the head report from the base one.
+// Functions traced only in one of the reports are reported as added or
+// removed, and are not considered newly covered or uncovered.
+func Diff(base, head *CoverageReport) *ReportDiff {
+	baseTraced, headTraced := toSet(base.FuncsTraced), toSet(head.FuncsTraced)
+	baseAck, headAck := toSet(base.FuncsAck), toSet(head.FuncsAck)
+
+	diff := &ReportDiff{
+		NewlyCovered:   []string{},
+		NewlyUncovered: []string{},
+		FuncsAdded:     []string{},
+		FuncsRemoved:   []string{},
+		CovByFuncBase:  base.CovByFunc,
+		CovByFuncHead:  head.CovByFunc,
+		CovByFuncDelta: head.CovByFunc - base.CovByFunc,
+	}
+
+	for name := range headTraced {
+		if _, ok := baseTraced[name]; !ok {
+			diff.FuncsAdded = append(diff.FuncsAdded, name)
+			continue
+		}
+		_, wasCovered := baseAck[name]
+		_, isCovered := headAck[name]
+		switch {
+		case isCovered && !wasCovered:
+			diff.NewlyCovered = append(diff.NewlyCovered, name)
+		case !isCovered && wasCovered:
+			diff.NewlyUncovered = append(diff.NewlyUncovered, name)
+		}
+	}
+	for name := range baseTraced {
+		if _, ok := headTraced[name]; !ok {
+			diff.FuncsRemoved = append(diff.FuncsRemoved, name)
+		}
+	}
+
+	for _, names := range [][]string{diff.NewlyCovered, diff.NewlyUncovered, diff.FuncsAdded, diff.FuncsRemoved} {
+		sort.Strings(names)
+	}
+
+	return diff
+}
+
+// WriteJSON writes the diff in JSON format.
+func (d *ReportDiff) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	return encoder.Encode(d)
+}
+
+// WriteText writes the diff in a human readable format.
+func (d *ReportDiff) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "Coverage by function: %.2f%% -> %.2f%% (%+.2f%%)\n",
+		d.CovByFuncBase, d.CovByFuncHead, d.CovByFuncDelta)
+
+	sections := []struct {
+		title  string
+		prefix string
+		names  []string
+	}{
+		{"Newly covered functions", "+", d.NewlyCovered},
+		{"Newly uncovered functions", "-", d.NewlyUncovered},
+		{"Added functions", "+", d.FuncsAdded},
+		{"Removed functions", "-", d.FuncsRemoved},
+	}
+	for _, section := range sections {
+		if len(section.names) == 0 {
+			continue
+		}
+		fmt.Fprintf(bw, "\n%s (%d):\n", section.title, len(section.names))
+		for _, name := range section.names {
+			fmt.Fprintf(bw, "  %s %s\n", section.prefix, name)
+		}
+	}
+
+	return bw.Flush()
+}
+
+func toSet(names []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		set[name] = struct{}{}
+	}
+
+	return set
+}
@@ -0,0 +1,43 @@
+package coverage_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxgio92/xcover/pkg/coverage"
+)
+
+func TestDiff(t *testing.T) {
+	base := coverage.NewCoverageReport(
+		coverage.WithReportFuncsTraced([]string{"foo", "bar", "baz", "old"}),
+		coverage.WithReportFuncsAck([]string{"bar", "baz", "old"}),
+		coverage.WithReportFuncsCov(75),
+	)
+	head := coverage.NewCoverageReport(
+		coverage.WithReportFuncsTraced([]string{"foo", "bar", "baz", "new"}),
+		coverage.WithReportFuncsAck([]string{"foo", "baz"}),
+		coverage.WithReportFuncsCov(50),
+	)
+
+	diff := coverage.Diff(base, head)
+	require.Equal(t, []string{"foo"}, diff.NewlyCovered)
+	require.Equal(t, []string{"bar"}, diff.NewlyUncovered)
+	require.Equal(t, []string{"new"}, diff.FuncsAdded)
+	require.Equal(t, []string{"old"}, diff.FuncsRemoved)
+	require.Equal(t, -25.0, diff.CovByFuncDelta)
+
+	var buf bytes.Buffer
+	require.NoError(t, diff.WriteText(&buf))
+	require.Contains(t, buf.String(), "75.00% -> 50.00% (-25.00%)")
+	require.Contains(t, buf.String(), "Newly covered functions (1):\n  + foo\n")
+	require.Contains(t, buf.String(), "Newly uncovered functions (1):\n  - bar\n")
+
+	buf.Reset()
+	require.NoError(t, diff.WriteJSON(&buf))
+	var parsed coverage.ReportDiff
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &parsed))
+	require.Equal(t, diff, &parsed)
+}
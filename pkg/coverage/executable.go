@@ -0,0 +1,111 @@
+package coverage
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+// Aggregate returns the report of the executables traced together, like
+// cooperating programs, with the report of each executable in Executables.
+// The functions of the aggregate are qualified by the name of their
+// executable, like "api:main.main", to be unique across the executables.
+func Aggregate(reports ...*CoverageReport) *CoverageReport {
+	names := executableNames(reports...)
+
+	aggregate := &CoverageReport{
+		FuncsTraced: []string{},
+		FuncsAck:    []string{},
+		Executables: make([]CoverageReport, 0, len(reports)),
+	}
+	for i, r := range reports {
+		qualify := func(name string) string {
+			return fmt.Sprintf("%s:%s", names[i], name)
+		}
+
+		for _, name := range r.FuncsTraced {
+			aggregate.FuncsTraced = append(aggregate.FuncsTraced, qualify(name))
+		}
+		for _, name := range r.FuncsAck {
+			aggregate.FuncsAck = append(aggregate.FuncsAck, qualify(name))
+		}
+		for name, hits := range r.FuncsHits {
+			if aggregate.FuncsHits == nil {
+				aggregate.FuncsHits = make(map[string]uint64)
+			}
+			aggregate.FuncsHits[qualify(name)] = hits
+		}
+		for label, ack := range r.FuncsAckByLabel {
+			if aggregate.FuncsAckByLabel == nil {
+				aggregate.FuncsAckByLabel = make(map[string][]string)
+			}
+			for _, name := range ack {
+				aggregate.FuncsAckByLabel[label] = append(aggregate.FuncsAckByLabel[label], qualify(name))
+			}
+		}
+		aggregate.Executables = append(aggregate.Executables, *r)
+	}
+
+	sort.Strings(aggregate.FuncsTraced)
+	sort.Strings(aggregate.FuncsAck)
+	for _, ack := range aggregate.FuncsAckByLabel {
+		sort.Strings(ack)
+	}
+	if len(aggregate.FuncsTraced) > 0 {
+		aggregate.CovByFunc = float64(len(aggregate.FuncsAck)) / float64(len(aggregate.FuncsTraced)) * 100
+	}
+
+	return aggregate
+}
+
+// executableNames returns the names qualifying the functions of each
+// executable, that are their base names, or their paths when the base
+// names are not unique.
+func executableNames(reports ...*CoverageReport) []string {
+	names := make([]string, 0, len(reports))
+	count := make(map[string]int, len(reports))
+	for _, r := range reports {
+		name := filepath.Base(r.ExePath)
+		names = append(names, name)
+		count[name]++
+	}
+	for i, r := range reports {
+		if count[names[i]] > 1 {
+			names[i] = r.ExePath
+		}
+	}
+
+	return names
+}
+
+// mergeExecutables merges the reports of the same executables of the
+// reports, keeping the executables in the order they are first found.
+func mergeExecutables(reports ...*CoverageReport) ([]CoverageReport, error) {
+	var paths []string
+	byPath := make(map[string][]*CoverageReport)
+
+	for _, r := range reports {
+		for i := range r.Executables {
+			exe := &r.Executables[i]
+			if _, ok := byPath[exe.ExePath]; !ok {
+				paths = append(paths, exe.ExePath)
+			}
+			byPath[exe.ExePath] = append(byPath[exe.ExePath], exe)
+		}
+	}
+
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	executables := make([]CoverageReport, 0, len(paths))
+	for _, path := range paths {
+		merged, err := Merge(byPath[path]...)
+		if err != nil {
+			return nil, err
+		}
+		executables = append(executables, *merged)
+	}
+
+	return executables, nil
+}
@@ -0,0 +1,111 @@
+package coverage_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxgio92/xcover/pkg/coverage"
+)
+
+func TestAggregate(t *testing.T) {
+	api := coverage.NewCoverageReport(
+		coverage.WithReportFuncsTraced([]string{"main.main", "main.serve"}),
+		coverage.WithReportFuncsAck([]string{"main.main"}),
+		coverage.WithReportFuncsAckByLabel(map[string][]string{"test1": {"main.main"}}),
+		coverage.WithReportExePath("/bin/api"),
+	)
+	worker := coverage.NewCoverageReport(
+		coverage.WithReportFuncsTraced([]string{"main.main", "main.work"}),
+		coverage.WithReportFuncsAck([]string{"main.main", "main.work"}),
+		coverage.WithReportFuncsHits(map[string]uint64{"main.work": 3}),
+		coverage.WithReportExePath("/bin/worker"),
+	)
+
+	aggregate := coverage.Aggregate(api, worker)
+	require.Equal(t, []string{"api:main.main", "api:main.serve", "worker:main.main", "worker:main.work"}, aggregate.FuncsTraced)
+	require.Equal(t, []string{"api:main.main", "worker:main.main", "worker:main.work"}, aggregate.FuncsAck)
+	require.Equal(t, map[string]uint64{"worker:main.work": 3}, aggregate.FuncsHits)
+	require.Equal(t, map[string][]string{"test1": {"api:main.main"}}, aggregate.FuncsAckByLabel)
+	require.Equal(t, 75.0, aggregate.CovByFunc)
+	require.Empty(t, aggregate.ExePath)
+	require.Equal(t, []coverage.CoverageReport{*api, *worker}, aggregate.Executables)
+
+	// Executables with the same base name.
+	other := coverage.NewCoverageReport(
+		coverage.WithReportFuncsTraced([]string{"main.main"}),
+		coverage.WithReportExePath("/opt/api"),
+	)
+	aggregate = coverage.Aggregate(api, other)
+	require.Equal(t, []string{"/bin/api:main.main", "/bin/api:main.serve", "/opt/api:main.main"}, aggregate.FuncsTraced)
+}
+
+func TestAggregate_WriteLCOV(t *testing.T) {
+	aggregate := coverage.Aggregate(
+		coverage.NewCoverageReport(
+			coverage.WithReportFuncsTraced([]string{"foo"}),
+			coverage.WithReportFuncsAck([]string{"foo"}),
+			coverage.WithReportExePath("api"),
+		),
+		coverage.NewCoverageReport(
+			coverage.WithReportFuncsTraced([]string{"foo"}),
+			coverage.WithReportExePath("worker"),
+		),
+	)
+
+	var buf bytes.Buffer
+	require.NoError(t, aggregate.WriteLCOV(&buf))
+
+	expected := `TN:
+SF:api
+FN:0,foo
+FNDA:1,foo
+FNF:1
+FNH:1
+end_of_record
+SF:worker
+FN:0,foo
+FNDA:0,foo
+FNF:1
+FNH:0
+end_of_record
+`
+	require.Equal(t, expected, buf.String())
+}
+
+func TestMerge_Executables(t *testing.T) {
+	a := coverage.Aggregate(
+		coverage.NewCoverageReport(
+			coverage.WithReportFuncsTraced([]string{"foo", "bar"}),
+			coverage.WithReportFuncsAck([]string{"foo"}),
+			coverage.WithReportExePath("api"),
+		),
+		coverage.NewCoverageReport(
+			coverage.WithReportFuncsTraced([]string{"baz"}),
+			coverage.WithReportExePath("worker"),
+		),
+	)
+	b := coverage.Aggregate(
+		coverage.NewCoverageReport(
+			coverage.WithReportFuncsTraced([]string{"foo", "bar"}),
+			coverage.WithReportFuncsAck([]string{"bar"}),
+			coverage.WithReportExePath("api"),
+		),
+		coverage.NewCoverageReport(
+			coverage.WithReportFuncsTraced([]string{"baz"}),
+			coverage.WithReportFuncsAck([]string{"baz"}),
+			coverage.WithReportExePath("worker"),
+		),
+	)
+
+	merged, err := coverage.Merge(a, b)
+	require.NoError(t, err)
+	require.Equal(t, []string{"api:bar", "api:foo", "worker:baz"}, merged.FuncsAck)
+	require.Equal(t, 100.0, merged.CovByFunc)
+	require.Len(t, merged.Executables, 2)
+	require.Equal(t, "api", merged.Executables[0].ExePath)
+	require.Equal(t, []string{"bar", "foo"}, merged.Executables[0].FuncsAck)
+	require.Equal(t, "worker", merged.Executables[1].ExePath)
+	require.Equal(t, 100.0, merged.Executables[1].CovByFunc)
+}
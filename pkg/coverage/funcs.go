@@ -0,0 +1,106 @@
+package coverage
+
+import (
+	"github.com/maxgio92/xcover/pkg/static"
+)
+
+// reportFunc is the coverage of a traced function.
+type reportFunc struct {
+	name string
+	file string
+	line int
+	hits uint64
+	// Path of the executable the function belongs to.
+	exePath string
+	// Whether the executable the function belongs to is built with Go.
+	goExe bool
+}
+
+// funcs returns the coverage of each traced function, that are the ones of
+// each executable of an aggregate.
+func (r *CoverageReport) funcs() []reportFunc {
+	if len(r.Executables) > 0 {
+		var funcs []reportFunc
+		for i := range r.Executables {
+			funcs = append(funcs, r.Executables[i].funcs()...)
+		}
+		return funcs
+	}
+
+	locations := r.sourceLocations()
+	goExe := isGoExecutable(r.ExePath)
+
+	ack := make(map[string]struct{}, len(r.FuncsAck))
+	for _, name := range r.FuncsAck {
+		ack[name] = struct{}{}
+	}
+
+	funcs := make([]reportFunc, 0, len(r.FuncsTraced))
+	for _, name := range r.FuncsTraced {
+		fn := reportFunc{name: name, exePath: r.ExePath, goExe: goExe}
+		if _, ok := ack[name]; ok {
+			fn.hits = 1
+			if hits, ok := r.FuncsHits[name]; ok && hits > 0 {
+				fn.hits = hits
+			}
+		}
+		if loc, ok := locations[name]; ok {
+			fn.file = loc.File
+			fn.line = loc.Line
+		}
+		funcs = append(funcs, fn)
+	}
+
+	return funcs
+}
+
+// sourceLocations returns the source locations of the functions, from the
+// report when present, otherwise from the DWARF debug info of the executable,
+// if available. Without the symbols, the names declared in more places, like
+// the local functions of different compilation units, are not located.
+func (r *CoverageReport) sourceLocations() map[string]FuncSource {
+	if len(r.FuncsSource) > 0 {
+		return r.FuncsSource
+	}
+	if r.ExePath == "" {
+		return nil
+	}
+	locations, err := static.GetFuncSourceLocations(r.ExePath)
+	if err != nil {
+		return nil
+	}
+
+	source := make(map[string]FuncSource, len(locations))
+	ambiguous := make(map[string]struct{})
+	for _, loc := range locations {
+		fs := FuncSource{
+			File:     loc.File,
+			Line:     loc.Line,
+			CompUnit: loc.CompUnit,
+		}
+		names := []string{loc.Name}
+		// The functions can be reported with their demangled names.
+		if demangled := static.Demangle(loc.Name); demangled != loc.Name {
+			names = append(names, demangled)
+		}
+		for _, name := range names {
+			if prev, ok := source[name]; ok && prev != fs {
+				ambiguous[name] = struct{}{}
+			}
+			source[name] = fs
+		}
+	}
+	for name := range ambiguous {
+		delete(source, name)
+	}
+
+	return source
+}
+
+func isGoExecutable(exePath string) bool {
+	if exePath == "" {
+		return false
+	}
+
+	return static.IsGoExecutable(exePath)
+}
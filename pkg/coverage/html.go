@@ -0,0 +1,124 @@
+package coverage
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/maxgio92/xcover/internal/settings"
+)
+
+//go:embed html.tmpl
+var htmlTemplate string
+
+var htmlTmpl = template.Must(template.New("report").Parse(htmlTemplate))
+
+type htmlReport struct {
+	Title    string
+	ExePath  string
+	Summary  htmlRollup
+	Packages []htmlRollup
+	Files    []htmlRollup
+	Funcs    []htmlFunc
+}
+
+type htmlRollup struct {
+	Name    string
+	Total   int
+	Covered int
+	Percent float64
+}
+
+type htmlFunc struct {
+	Name    string
+	Package string
+	File    string
+	Line    int
+	Hits    uint64
+	Covered bool
+}
+
+// WriteHTML writes the report as a self-contained HTML page, with a sortable
+// and filterable table of the traced functions and the coverage rolled up by
+// package and by source file.
+func (r *CoverageReport) WriteHTML(w io.Writer) error {
+	funcs := r.funcs()
+
+	exePath := r.ExePath
+	if len(r.Executables) > 0 {
+		paths := make([]string, 0, len(r.Executables))
+		for _, exe := range r.Executables {
+			paths = append(paths, exe.ExePath)
+		}
+		exePath = strings.Join(paths, ", ")
+	}
+
+	doc := htmlReport{
+		Title:   fmt.Sprintf("%s coverage report", settings.CmdName),
+		ExePath: exePath,
+		Summary: htmlRollup{Name: exePath},
+		Funcs:   make([]htmlFunc, 0, len(funcs)),
+	}
+	packages := make(map[string]*htmlRollup)
+	files := make(map[string]*htmlRollup)
+
+	for _, fn := range funcs {
+		pkg, _ := funcScope(fn)
+		covered := fn.hits > 0
+
+		doc.Funcs = append(doc.Funcs, htmlFunc{
+			Name:    fn.name,
+			Package: pkg,
+			File:    fn.file,
+			Line:    fn.line,
+			Hits:    fn.hits,
+			Covered: covered,
+		})
+
+		file := fn.file
+		if file == "" {
+			file = fn.exePath
+		}
+		for _, rollup := range []*htmlRollup{&doc.Summary, getRollup(packages, pkg), getRollup(files, file)} {
+			rollup.Total++
+			if covered {
+				rollup.Covered++
+			}
+		}
+	}
+	sort.Slice(doc.Funcs, func(i, j int) bool {
+		return doc.Funcs[i].Name < doc.Funcs[j].Name
+	})
+
+	doc.Summary.Percent = rate(doc.Summary.Covered, doc.Summary.Total) * 100
+	doc.Packages = sortedRollups(packages)
+	doc.Files = sortedRollups(files)
+
+	return htmlTmpl.Execute(w, doc)
+}
+
+func getRollup(rollups map[string]*htmlRollup, name string) *htmlRollup {
+	rollup, ok := rollups[name]
+	if !ok {
+		rollup = &htmlRollup{Name: name}
+		rollups[name] = rollup
+	}
+
+	return rollup
+}
+
+func sortedRollups(rollups map[string]*htmlRollup) []htmlRollup {
+	sorted := make([]htmlRollup, 0, len(rollups))
+	for _, rollup := range rollups {
+		rollup.Percent = rate(rollup.Covered, rollup.Total) * 100
+		sorted = append(sorted, *rollup)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	return sorted
+}
@@ -0,0 +1,28 @@
+package coverage_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxgio92/xcover/pkg/coverage"
+)
+
+func TestWriteHTML(t *testing.T) {
+	report := coverage.NewCoverageReport(
+		coverage.WithReportFuncsTraced([]string{"main.fooFunction", "main.barFunction", "<script>"}),
+		coverage.WithReportFuncsAck([]string{"main.fooFunction"}),
+		coverage.WithReportExePath(testBinary),
+	)
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteHTML(&buf))
+
+	out := buf.String()
+	require.Contains(t, out, "<!DOCTYPE html>")
+	require.Contains(t, out, "1/3 (33.33%)")
+	require.Contains(t, out, "<td>main.fooFunction</td>")
+	require.Contains(t, out, "test.go")
+	require.Contains(t, out, "&lt;script&gt;")
+}
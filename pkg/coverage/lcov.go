@@ -0,0 +1,66 @@
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// WriteLCOV writes the report as an LCOV tracefile, with one FN/FNDA record
+// per traced function.
+// Functions are grouped by the source file they are declared in, as resolved
+// from the DWARF debug info of the executable when present. Functions whose
+// source file is unknown are grouped under the executable path.
+func (r *CoverageReport) WriteLCOV(w io.Writer) error {
+	files := r.lcovFiles()
+
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "TN:")
+	for _, path := range paths {
+		funcs := files[path]
+		sort.Slice(funcs, func(i, j int) bool {
+			if funcs[i].line != funcs[j].line {
+				return funcs[i].line < funcs[j].line
+			}
+			return funcs[i].name < funcs[j].name
+		})
+
+		var hit int
+		fmt.Fprintf(bw, "SF:%s\n", path)
+		for _, fn := range funcs {
+			fmt.Fprintf(bw, "FN:%d,%s\n", fn.line, fn.name)
+		}
+		for _, fn := range funcs {
+			fmt.Fprintf(bw, "FNDA:%d,%s\n", fn.hits, fn.name)
+			if fn.hits > 0 {
+				hit++
+			}
+		}
+		fmt.Fprintf(bw, "FNF:%d\n", len(funcs))
+		fmt.Fprintf(bw, "FNH:%d\n", hit)
+		fmt.Fprintln(bw, "end_of_record")
+	}
+
+	return bw.Flush()
+}
+
+// lcovFiles returns the traced functions grouped by source file.
+func (r *CoverageReport) lcovFiles() map[string][]reportFunc {
+	files := make(map[string][]reportFunc)
+	for _, fn := range r.funcs() {
+		path := fn.file
+		if path == "" {
+			path = fn.exePath
+		}
+		files[path] = append(files[path], fn)
+	}
+
+	return files
+}
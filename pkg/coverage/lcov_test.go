@@ -0,0 +1,75 @@
+package coverage_test
+
+import (
+	"bytes"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxgio92/xcover/pkg/coverage"
+)
+
+func TestWriteLCOV(t *testing.T) {
+	report := coverage.NewCoverageReport(
+		coverage.WithReportFuncsTraced([]string{"foo", "bar", "baz"}),
+		coverage.WithReportFuncsAck([]string{"foo", "baz"}),
+		coverage.WithReportFuncsHits(map[string]uint64{"foo": 3}),
+		coverage.WithReportExePath("mybin"),
+	)
+
+	var buf bytes.Buffer
+	err := report.WriteLCOV(&buf)
+	require.NoError(t, err)
+
+	expected := `TN:
+SF:mybin
+FN:0,bar
+FN:0,baz
+FN:0,foo
+FNDA:0,bar
+FNDA:1,baz
+FNDA:3,foo
+FNF:3
+FNH:2
+end_of_record
+`
+	require.Equal(t, expected, buf.String())
+}
+
+func TestWriteReportFormat(t *testing.T) {
+	report := coverage.NewCoverageReport(
+		coverage.WithReportFuncsTraced([]string{"foo"}),
+		coverage.WithReportFuncsAck([]string{"foo"}),
+	)
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteReportFormat(&buf, coverage.ReportFormatLCOV))
+	require.Contains(t, buf.String(), "FNDA:1,foo")
+
+	buf.Reset()
+	require.NoError(t, report.WriteReportFormat(&buf, coverage.ReportFormatJSON))
+	require.Contains(t, buf.String(), `"funcs_ack":["foo"]`)
+
+	err := report.WriteReportFormat(&buf, coverage.ReportFormat("xml"))
+	require.ErrorIs(t, err, coverage.ErrReportFormatUnknown)
+	require.ErrorIs(t, coverage.ReportFormat("xml").Validate(), coverage.ErrReportFormatUnknown)
+	require.Equal(t, "info", coverage.ReportFormatLCOV.FileExt())
+}
+
+func TestWriteLCOV_DuplicateNames(t *testing.T) {
+	exePath := path.Join("..", "trace", "testdata", "dupstatic")
+	report := coverage.NewCoverageReport(
+		coverage.WithReportFuncsTraced([]string{"main", "helper"}),
+		coverage.WithReportFuncsAck([]string{"main", "helper"}),
+		coverage.WithReportExePath(exePath),
+	)
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteLCOV(&buf))
+
+	// The helper functions of different compilation units are not located
+	// by name.
+	require.Regexp(t, `SF:.*dupstatic_b\.c\nFN:10,main\n`, buf.String())
+	require.Contains(t, buf.String(), "SF:"+exePath+"\nFN:0,helper\n")
+}
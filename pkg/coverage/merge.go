@@ -0,0 +1,113 @@
+package coverage
+
+import (
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrMergeNoReports       = errors.New("no reports to merge")
+	ErrMergeExePathMismatch = errors.New("reports have different executable paths")
+	ErrMergeBuildIDMismatch = errors.New("reports have different build IDs")
+)
+
+// Merge merges the reports of the same executable into a single report,
+// with the union of the functions traced and acknowledged, and the sum of
+// the function hits, if any, also for each ELF object traced, each label and
+// each executable of an aggregate.
+// The executable path and the build ID, when present, must match between
+// all the reports.
+func Merge(reports ...*CoverageReport) (*CoverageReport, error) {
+	if len(reports) == 0 {
+		return nil, ErrMergeNoReports
+	}
+
+	merged := &CoverageReport{
+		ExePath: reports[0].ExePath,
+	}
+	traced := make(map[string]struct{})
+	ack := make(map[string]struct{})
+	ackByLabel := make(map[string]map[string]struct{})
+
+	for _, r := range reports {
+		if r.ExePath != merged.ExePath {
+			return nil, errors.Wrapf(ErrMergeExePathMismatch, "%s != %s", r.ExePath, merged.ExePath)
+		}
+		if r.BuildID != "" {
+			if merged.BuildID != "" && r.BuildID != merged.BuildID {
+				return nil, errors.Wrapf(ErrMergeBuildIDMismatch, "%s != %s", r.BuildID, merged.BuildID)
+			}
+			merged.BuildID = r.BuildID
+		}
+
+		for _, name := range r.FuncsTraced {
+			traced[name] = struct{}{}
+		}
+		for _, name := range r.FuncsAck {
+			ack[name] = struct{}{}
+		}
+		for name, source := range r.FuncsSource {
+			if merged.FuncsSource == nil {
+				merged.FuncsSource = make(map[string]FuncSource)
+			}
+			merged.FuncsSource[name] = source
+		}
+		for name, aliases := range r.FuncsAliases {
+			if merged.FuncsAliases == nil {
+				merged.FuncsAliases = make(map[string][]string)
+			}
+			merged.FuncsAliases[name] = aliases
+		}
+		for label, names := range r.FuncsAckByLabel {
+			if ackByLabel[label] == nil {
+				ackByLabel[label] = make(map[string]struct{})
+			}
+			for _, name := range names {
+				ackByLabel[label][name] = struct{}{}
+			}
+		}
+		for name, hits := range r.FuncsHits {
+			if merged.FuncsHits == nil {
+				merged.FuncsHits = make(map[string]uint64)
+			}
+			merged.FuncsHits[name] += hits
+		}
+	}
+
+	objects, err := mergeObjects(reports...)
+	if err != nil {
+		return nil, err
+	}
+	merged.Objects = objects
+
+	executables, err := mergeExecutables(reports...)
+	if err != nil {
+		return nil, err
+	}
+	merged.Executables = executables
+
+	merged.FuncsTraced = sortedKeys(traced)
+	merged.FuncsAck = sortedKeys(ack)
+	for label, names := range ackByLabel {
+		if merged.FuncsAckByLabel == nil {
+			merged.FuncsAckByLabel = make(map[string][]string)
+		}
+		merged.FuncsAckByLabel[label] = sortedKeys(names)
+	}
+	if len(merged.FuncsTraced) > 0 {
+		merged.CovByFunc = float64(len(merged.FuncsAck)) / float64(len(merged.FuncsTraced)) * 100
+	}
+
+	return merged, nil
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,108 @@
+package coverage_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxgio92/xcover/pkg/coverage"
+)
+
+func TestMerge(t *testing.T) {
+	a := coverage.NewCoverageReport(
+		coverage.WithReportFuncsTraced([]string{"foo", "bar", "baz", "qux"}),
+		coverage.WithReportFuncsAck([]string{"foo"}),
+		coverage.WithReportFuncsHits(map[string]uint64{"foo": 2}),
+		coverage.WithReportFuncsSource(map[string]coverage.FuncSource{"foo": {File: "foo.c", Line: 1}}),
+		coverage.WithReportExePath("mybin"),
+		coverage.WithReportBuildID("abc"),
+	)
+	b := coverage.NewCoverageReport(
+		coverage.WithReportFuncsTraced([]string{"foo", "bar", "baz", "qux"}),
+		coverage.WithReportFuncsAck([]string{"foo", "bar"}),
+		coverage.WithReportFuncsHits(map[string]uint64{"foo": 3, "bar": 1}),
+		coverage.WithReportExePath("mybin"),
+		coverage.WithReportBuildID("abc"),
+	)
+
+	merged, err := coverage.Merge(a, b)
+	require.NoError(t, err)
+	require.Equal(t, []string{"bar", "baz", "foo", "qux"}, merged.FuncsTraced)
+	require.Equal(t, []string{"bar", "foo"}, merged.FuncsAck)
+	require.Equal(t, map[string]uint64{"foo": 5, "bar": 1}, merged.FuncsHits)
+	require.Equal(t, map[string]coverage.FuncSource{"foo": {File: "foo.c", Line: 1}}, merged.FuncsSource)
+	require.Equal(t, 50.0, merged.CovByFunc)
+	require.Equal(t, "mybin", merged.ExePath)
+	require.Equal(t, "abc", merged.BuildID)
+}
+
+func TestMerge_Errors(t *testing.T) {
+	_, err := coverage.Merge()
+	require.ErrorIs(t, err, coverage.ErrMergeNoReports)
+
+	_, err = coverage.Merge(
+		coverage.NewCoverageReport(coverage.WithReportExePath("foo")),
+		coverage.NewCoverageReport(coverage.WithReportExePath("bar")),
+	)
+	require.ErrorIs(t, err, coverage.ErrMergeExePathMismatch)
+
+	_, err = coverage.Merge(
+		coverage.NewCoverageReport(coverage.WithReportExePath("foo"), coverage.WithReportBuildID("abc")),
+		coverage.NewCoverageReport(coverage.WithReportExePath("foo"), coverage.WithReportBuildID("def")),
+	)
+	require.ErrorIs(t, err, coverage.ErrMergeBuildIDMismatch)
+}
+
+func TestMerge_Objects(t *testing.T) {
+	a := coverage.NewCoverageReport(
+		coverage.WithReportFuncsTraced([]string{"foo", "bar", "lib_foo"}),
+		coverage.WithReportFuncsAck([]string{"foo"}),
+		coverage.WithReportExePath("mybin"),
+		coverage.WithReportObjects([]coverage.ObjectCoverage{
+			{Path: "mybin", FuncsTraced: []string{"foo", "bar"}, FuncsAck: []string{"foo"}},
+			{Path: "libfoo.so", FuncsTraced: []string{"lib_foo"}, BuildID: "abc"},
+		}),
+	)
+	b := coverage.NewCoverageReport(
+		coverage.WithReportFuncsTraced([]string{"foo", "bar", "lib_foo"}),
+		coverage.WithReportFuncsAck([]string{"lib_foo"}),
+		coverage.WithReportExePath("mybin"),
+		coverage.WithReportObjects([]coverage.ObjectCoverage{
+			{Path: "mybin", FuncsTraced: []string{"foo", "bar"}},
+			{Path: "libfoo.so", FuncsTraced: []string{"lib_foo"}, FuncsAck: []string{"lib_foo"}, BuildID: "abc"},
+		}),
+	)
+
+	merged, err := coverage.Merge(a, b)
+	require.NoError(t, err)
+	require.Equal(t, []coverage.ObjectCoverage{
+		{Path: "mybin", FuncsTraced: []string{"bar", "foo"}, FuncsAck: []string{"foo"}, CovByFunc: 50},
+		{Path: "libfoo.so", FuncsTraced: []string{"lib_foo"}, FuncsAck: []string{"lib_foo"}, CovByFunc: 100, BuildID: "abc"},
+	}, merged.Objects)
+
+	b.Objects[1].BuildID = "def"
+	_, err = coverage.Merge(a, b)
+	require.ErrorIs(t, err, coverage.ErrMergeBuildIDMismatch)
+}
+
+func TestMerge_FuncsAckByLabel(t *testing.T) {
+	a := coverage.NewCoverageReport(
+		coverage.WithReportFuncsTraced([]string{"foo", "bar"}),
+		coverage.WithReportFuncsAck([]string{"foo", "bar"}),
+		coverage.WithReportFuncsAckByLabel(map[string][]string{"test1": {"foo"}, "test2": {"bar"}}),
+		coverage.WithReportExePath("mybin"),
+	)
+	b := coverage.NewCoverageReport(
+		coverage.WithReportFuncsTraced([]string{"foo", "bar"}),
+		coverage.WithReportFuncsAck([]string{"foo", "bar"}),
+		coverage.WithReportFuncsAckByLabel(map[string][]string{"test1": {"bar"}}),
+		coverage.WithReportExePath("mybin"),
+	)
+
+	merged, err := coverage.Merge(a, b)
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{
+		"test1": {"bar", "foo"},
+		"test2": {"bar"},
+	}, merged.FuncsAckByLabel)
+}
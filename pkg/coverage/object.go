@@ -0,0 +1,66 @@
+package coverage
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ObjectCoverage is the coverage of the functions of an ELF object, that is
+// the executable or a shared library.
+type ObjectCoverage struct {
+	Path        string   `json:"path"`
+	FuncsTraced []string `json:"funcs_traced"`
+	FuncsAck    []string `json:"funcs_ack"`
+	CovByFunc   float64  `json:"cov_by_func"`
+	BuildID     string   `json:"build_id,omitempty"`
+}
+
+// mergeObjects merges the coverage of the same ELF objects of the reports,
+// keeping the objects in the order they are first found.
+func mergeObjects(reports ...*CoverageReport) ([]ObjectCoverage, error) {
+	var paths []string
+	merged := make(map[string]*ObjectCoverage)
+	traced := make(map[string]map[string]struct{})
+	ack := make(map[string]map[string]struct{})
+
+	for _, r := range reports {
+		for _, obj := range r.Objects {
+			m, ok := merged[obj.Path]
+			if !ok {
+				m = &ObjectCoverage{Path: obj.Path}
+				merged[obj.Path] = m
+				traced[obj.Path] = make(map[string]struct{})
+				ack[obj.Path] = make(map[string]struct{})
+				paths = append(paths, obj.Path)
+			}
+			if obj.BuildID != "" {
+				if m.BuildID != "" && obj.BuildID != m.BuildID {
+					return nil, errors.Wrapf(ErrMergeBuildIDMismatch, "%s: %s != %s", obj.Path, obj.BuildID, m.BuildID)
+				}
+				m.BuildID = obj.BuildID
+			}
+			for _, name := range obj.FuncsTraced {
+				traced[obj.Path][name] = struct{}{}
+			}
+			for _, name := range obj.FuncsAck {
+				ack[obj.Path][name] = struct{}{}
+			}
+		}
+	}
+
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	objects := make([]ObjectCoverage, 0, len(paths))
+	for _, path := range paths {
+		obj := merged[path]
+		obj.FuncsTraced = sortedKeys(traced[path])
+		obj.FuncsAck = sortedKeys(ack[path])
+		if len(obj.FuncsTraced) > 0 {
+			obj.CovByFunc = float64(len(obj.FuncsAck)) / float64(len(obj.FuncsTraced)) * 100
+		}
+		objects = append(objects, *obj)
+	}
+
+	return objects, nil
+}
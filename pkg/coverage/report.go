@@ -3,13 +3,85 @@ package coverage
 import (
 	"encoding/json"
 	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+type ReportFormat string
+
+const (
+	ReportFormatJSON      ReportFormat = "json"
+	ReportFormatLCOV      ReportFormat = "lcov"
+	ReportFormatCobertura ReportFormat = "cobertura"
+	ReportFormatHTML      ReportFormat = "html"
 )
 
+var ErrReportFormatUnknown = errors.New("unknown report format")
+
+var reportFormatFileExts = map[ReportFormat]string{
+	ReportFormatJSON:      "json",
+	ReportFormatLCOV:      "info",
+	ReportFormatCobertura: "xml",
+	ReportFormatHTML:      "html",
+}
+
+// ReportFormats returns the supported report formats.
+func ReportFormats() []ReportFormat {
+	return []ReportFormat{ReportFormatJSON, ReportFormatLCOV, ReportFormatCobertura, ReportFormatHTML}
+}
+
+// ReportFormatNames returns the names of the supported report formats.
+func ReportFormatNames() []string {
+	names := make([]string, 0, len(ReportFormats()))
+	for _, f := range ReportFormats() {
+		names = append(names, string(f))
+	}
+
+	return names
+}
+
+// FileExt returns the file extension of the report format.
+func (f ReportFormat) FileExt() string {
+	return reportFormatFileExts[f]
+}
+
+// Validate returns an error if the report format is not supported.
+func (f ReportFormat) Validate() error {
+	if _, ok := reportFormatFileExts[f]; !ok {
+		return errors.Wrap(ErrReportFormatUnknown, string(f))
+	}
+
+	return nil
+}
+
 type CoverageReport struct {
-	FuncsTraced []string `json:"funcs_traced"`
-	FuncsAck    []string `json:"funcs_ack"`
-	CovByFunc   float64  `json:"cov_by_func"`
-	ExePath     string   `json:"exe_path"`
+	FuncsTraced []string              `json:"funcs_traced"`
+	FuncsAck    []string              `json:"funcs_ack"`
+	FuncsHits   map[string]uint64     `json:"funcs_hits,omitempty"`
+	FuncsSource map[string]FuncSource `json:"funcs_source,omitempty"`
+	// FuncsAliases are the other names of the functions, that are the
+	// symbols at the same address, traced once.
+	FuncsAliases map[string][]string `json:"funcs_aliases,omitempty"`
+	// FuncsAckByLabel are the functions acknowledged while each label was
+	// marked, like the name of the test being run.
+	FuncsAckByLabel map[string][]string `json:"funcs_ack_by_label,omitempty"`
+	CovByFunc       float64             `json:"cov_by_func"`
+	ExePath         string              `json:"exe_path"`
+	BuildID         string              `json:"build_id,omitempty"`
+	// Objects is the coverage of each ELF object traced, when
+	// shared libraries are traced along with the executable.
+	Objects []ObjectCoverage `json:"objects,omitempty"`
+	// Executables is the report of each executable, when more executables
+	// are traced together. The report is then their aggregate.
+	Executables []CoverageReport `json:"executables,omitempty"`
+}
+
+// FuncSource is the location in the source code where a function is declared.
+type FuncSource struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	CompUnit string `json:"comp_unit,omitempty"`
 }
 
 type CoverageReportOption func(*CoverageReport)
@@ -35,6 +107,30 @@ func WithReportFuncsAck(ack []string) CoverageReportOption {
 	}
 }
 
+func WithReportFuncsHits(hits map[string]uint64) CoverageReportOption {
+	return func(o *CoverageReport) {
+		o.FuncsHits = hits
+	}
+}
+
+func WithReportFuncsSource(source map[string]FuncSource) CoverageReportOption {
+	return func(o *CoverageReport) {
+		o.FuncsSource = source
+	}
+}
+
+func WithReportFuncsAliases(aliases map[string][]string) CoverageReportOption {
+	return func(o *CoverageReport) {
+		o.FuncsAliases = aliases
+	}
+}
+
+func WithReportFuncsAckByLabel(ackByLabel map[string][]string) CoverageReportOption {
+	return func(o *CoverageReport) {
+		o.FuncsAckByLabel = ackByLabel
+	}
+}
+
 func WithReportFuncsCov(cov float64) CoverageReportOption {
 	return func(o *CoverageReport) {
 		o.CovByFunc = cov
@@ -47,7 +143,62 @@ func WithReportExePath(exePath string) CoverageReportOption {
 	}
 }
 
+func WithReportBuildID(buildID string) CoverageReportOption {
+	return func(o *CoverageReport) {
+		o.BuildID = buildID
+	}
+}
+
+func WithReportObjects(objects []ObjectCoverage) CoverageReportOption {
+	return func(o *CoverageReport) {
+		o.Objects = objects
+	}
+}
+
+func WithReportExecutables(executables []CoverageReport) CoverageReportOption {
+	return func(o *CoverageReport) {
+		o.Executables = executables
+	}
+}
+
+// ReadReport reads a report in JSON format from r.
+func ReadReport(r io.Reader) (*CoverageReport, error) {
+	report := new(CoverageReport)
+	if err := json.NewDecoder(r).Decode(report); err != nil {
+		return nil, errors.Wrap(err, "failed to decode report")
+	}
+
+	return report, nil
+}
+
+// ReadReportFile reads a report in JSON format from the file at path.
+func ReadReportFile(path string) (*CoverageReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open report")
+	}
+	defer f.Close()
+
+	return ReadReport(f)
+}
+
 func (r *CoverageReport) WriteReport(w io.Writer) error {
 	encoder := json.NewEncoder(w)
 	return encoder.Encode(r)
 }
+
+// WriteReportFormat writes the report to w in the specified format.
+func (r *CoverageReport) WriteReportFormat(w io.Writer, format ReportFormat) error {
+	switch format {
+	case ReportFormatJSON:
+		return r.WriteReport(w)
+	case ReportFormatLCOV:
+		return r.WriteLCOV(w)
+	case ReportFormatCobertura:
+		return r.WriteCobertura(w)
+	case ReportFormatHTML:
+		return r.WriteHTML(w)
+	default:
+		return errors.Wrap(ErrReportFormatUnknown, string(format))
+	}
+}
@@ -62,3 +62,43 @@ func TestWriteReportToBufferContainsExpectedFields(t *testing.T) {
 	require.True(t, strings.Contains(output, "cov_by_func"))
 	require.True(t, strings.Contains(output, "exe_path"))
 }
+
+func TestWriteReportFuncsHits(t *testing.T) {
+	report := coverage.NewCoverageReport(
+		coverage.WithReportFuncsTraced([]string{"foo", "bar"}),
+		coverage.WithReportFuncsAck([]string{"foo"}),
+		coverage.WithReportFuncsHits(map[string]uint64{"foo": 42}),
+	)
+
+	var buf bytes.Buffer
+	err := report.WriteReport(&buf)
+	require.NoError(t, err)
+	require.Contains(t, buf.String(), `"funcs_hits":{"foo":42}`)
+
+	report = coverage.NewCoverageReport(
+		coverage.WithReportFuncsAck([]string{"foo"}),
+	)
+	buf.Reset()
+	err = report.WriteReport(&buf)
+	require.NoError(t, err)
+	require.NotContains(t, buf.String(), "funcs_hits")
+}
+
+func TestReadReport(t *testing.T) {
+	report := coverage.NewCoverageReport(
+		coverage.WithReportFuncsTraced([]string{"foo", "bar"}),
+		coverage.WithReportFuncsAck([]string{"foo"}),
+		coverage.WithReportFuncsCov(50),
+		coverage.WithReportExePath("mybin"),
+	)
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteReport(&buf))
+
+	parsed, err := coverage.ReadReport(&buf)
+	require.NoError(t, err)
+	require.Equal(t, report, parsed)
+
+	_, err = coverage.ReadReport(strings.NewReader("{"))
+	require.Error(t, err)
+}
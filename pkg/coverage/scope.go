@@ -0,0 +1,78 @@
+package coverage
+
+import (
+	"strings"
+	"unicode"
+)
+
+// goFuncScope returns the package path and the receiver type of a Go function
+// symbol name, like "github.com/foo/bar.(*Baz).Run".
+func goFuncScope(name string) (pkg, recv string) {
+	start := strings.LastIndex(name, "/") + 1
+	dot := strings.Index(name[start:], ".")
+	if dot < 0 {
+		return "", ""
+	}
+	pkg = name[:start+dot]
+
+	rest := name[start+dot+1:]
+	if strings.HasPrefix(rest, "(") {
+		if end := strings.Index(rest, ")"); end > 0 {
+			recv = strings.TrimPrefix(rest[1:end], "*")
+		}
+	}
+
+	return pkg, recv
+}
+
+// cxxFuncScope returns the scopes enclosing a C++ function, from either its
+// mangled name (Itanium ABI nested names only, like "_ZN3foo3Bar3runEv")
+// or its demangled name, like "foo::Bar::run()".
+func cxxFuncScope(name string) []string {
+	if strings.HasPrefix(name, "_ZN") {
+		return mangledNestedScope(name[len("_ZN"):])
+	}
+
+	if i := strings.Index(name, "("); i >= 0 {
+		name = name[:i]
+	}
+	parts := strings.Split(name, "::")
+	if len(parts) < 2 {
+		return nil
+	}
+
+	return parts[:len(parts)-1]
+}
+
+func mangledNestedScope(s string) []string {
+	// Skip CV and ref qualifiers.
+	s = strings.TrimLeft(s, "rVKRO")
+
+	var names []string
+	if strings.HasPrefix(s, "St") {
+		names = append(names, "std")
+		s = s[len("St"):]
+	}
+	for len(s) > 0 && unicode.IsDigit(rune(s[0])) {
+		var n int
+		for len(s) > 0 && unicode.IsDigit(rune(s[0])) {
+			n = n*10 + int(s[0]-'0')
+			s = s[1:]
+		}
+		if n > len(s) {
+			return nil
+		}
+		names = append(names, s[:n])
+		s = s[n:]
+	}
+
+	// Constructors and destructors are named after their class.
+	if strings.HasPrefix(s, "C") || strings.HasPrefix(s, "D") {
+		return names
+	}
+	if len(names) < 2 {
+		return nil
+	}
+
+	return names[:len(names)-1]
+}
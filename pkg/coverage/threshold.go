@@ -0,0 +1,138 @@
+package coverage
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type ThresholdScope string
+
+const (
+	// ThresholdScopeAll applies the threshold to the coverage by function of the report.
+	ThresholdScopeAll ThresholdScope = "all"
+	// ThresholdScopePattern applies the threshold to the functions whose name matches a regex pattern.
+	ThresholdScopePattern ThresholdScope = "pattern"
+	// ThresholdScopePackage applies the threshold to the functions of a package.
+	ThresholdScopePackage ThresholdScope = "package"
+)
+
+var ErrThresholdInvalid = errors.New("invalid threshold")
+
+// Threshold is the minimum coverage by function, in percentage, required for a
+// group of functions.
+type Threshold struct {
+	Scope ThresholdScope
+	// Target is the regex pattern or the package the threshold applies to,
+	// depending on the scope.
+	Target string
+	Min    float64
+}
+
+// ParseThreshold parses a threshold in the form TARGET=MIN for the scope.
+func ParseThreshold(scope ThresholdScope, s string) (Threshold, error) {
+	i := strings.LastIndex(s, "=")
+	if i < 0 {
+		return Threshold{}, errors.Wrapf(ErrThresholdInvalid, "%s: expected TARGET=MIN", s)
+	}
+	min, err := strconv.ParseFloat(s[i+1:], 64)
+	if err != nil {
+		return Threshold{}, errors.Wrapf(ErrThresholdInvalid, "%s: %v", s, err)
+	}
+
+	return Threshold{Scope: scope, Target: s[:i], Min: min}, nil
+}
+
+// ThresholdViolation is a threshold not met by the coverage of the report.
+type ThresholdViolation struct {
+	Threshold Threshold
+	Coverage  float64
+}
+
+// ThresholdError is the error returned when some thresholds are not met.
+type ThresholdError struct {
+	Violations []ThresholdViolation
+}
+
+func (e *ThresholdError) Error() string {
+	msgs := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		switch v.Threshold.Scope {
+		case ThresholdScopeAll:
+			msgs = append(msgs, fmt.Sprintf("coverage by function %.2f%% is below %.2f%%", v.Coverage, v.Threshold.Min))
+		default:
+			msgs = append(msgs, fmt.Sprintf("coverage by function of %s %s %.2f%% is below %.2f%%",
+				v.Threshold.Scope, v.Threshold.Target, v.Coverage, v.Threshold.Min))
+		}
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// CheckThresholds returns a *ThresholdError if the coverage by function of the
+// report does not meet any of the thresholds.
+// Groups of functions without any traced function are considered not covered.
+func (r *CoverageReport) CheckThresholds(thresholds ...Threshold) error {
+	var violations []ThresholdViolation
+
+	var funcs []reportFunc
+	for _, t := range thresholds {
+		if t.Scope != ThresholdScopeAll && funcs == nil {
+			funcs = r.funcs()
+		}
+
+		cov, err := r.thresholdCoverage(t, funcs)
+		if err != nil {
+			return err
+		}
+		if cov < t.Min {
+			violations = append(violations, ThresholdViolation{Threshold: t, Coverage: cov})
+		}
+	}
+
+	if len(violations) > 0 {
+		return &ThresholdError{Violations: violations}
+	}
+
+	return nil
+}
+
+func (r *CoverageReport) thresholdCoverage(t Threshold, funcs []reportFunc) (float64, error) {
+	var match func(fn reportFunc) bool
+
+	switch t.Scope {
+	case ThresholdScopeAll:
+		return r.CovByFunc, nil
+	case ThresholdScopePattern:
+		re, err := regexp.Compile(t.Target)
+		if err != nil {
+			return 0, errors.Wrapf(ErrThresholdInvalid, "%s: %v", t.Target, err)
+		}
+		match = func(fn reportFunc) bool {
+			return re.MatchString(fn.name)
+		}
+	case ThresholdScopePackage:
+		match = func(fn reportFunc) bool {
+			pkg, _ := funcScope(fn)
+			return pkg == t.Target
+		}
+	default:
+		return 0, errors.Wrapf(ErrThresholdInvalid, "unknown scope %s", t.Scope)
+	}
+
+	var covered, total int
+	for _, fn := range funcs {
+		if !match(fn) {
+			continue
+		}
+		total++
+		if fn.hits > 0 {
+			covered++
+		}
+	}
+
+	return rate(covered, total) * 100, nil
+}
@@ -0,0 +1,65 @@
+package coverage_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxgio92/xcover/pkg/coverage"
+)
+
+func TestParseThreshold(t *testing.T) {
+	th, err := coverage.ParseThreshold(coverage.ThresholdScopePattern, "^main\\.=75.5")
+	require.NoError(t, err)
+	require.Equal(t, coverage.Threshold{Scope: coverage.ThresholdScopePattern, Target: "^main\\.", Min: 75.5}, th)
+
+	_, err = coverage.ParseThreshold(coverage.ThresholdScopePackage, "main")
+	require.ErrorIs(t, err, coverage.ErrThresholdInvalid)
+
+	_, err = coverage.ParseThreshold(coverage.ThresholdScopePackage, "main=abc")
+	require.ErrorIs(t, err, coverage.ErrThresholdInvalid)
+}
+
+func TestCheckThresholds(t *testing.T) {
+	report := coverage.NewCoverageReport(
+		coverage.WithReportFuncsTraced([]string{"main.foo", "main.bar", "runtime.baz", "runtime.qux"}),
+		coverage.WithReportFuncsAck([]string{"main.foo", "main.bar", "runtime.baz"}),
+		coverage.WithReportFuncsCov(75),
+	)
+
+	require.NoError(t, report.CheckThresholds(
+		coverage.Threshold{Scope: coverage.ThresholdScopeAll, Min: 75},
+		coverage.Threshold{Scope: coverage.ThresholdScopePattern, Target: "^main\\.", Min: 100},
+	))
+
+	err := report.CheckThresholds(
+		coverage.Threshold{Scope: coverage.ThresholdScopeAll, Min: 80},
+		coverage.Threshold{Scope: coverage.ThresholdScopePattern, Target: "^runtime\\.", Min: 60},
+		coverage.Threshold{Scope: coverage.ThresholdScopePattern, Target: "^nonexistent$", Min: 1},
+	)
+	var thErr *coverage.ThresholdError
+	require.ErrorAs(t, err, &thErr)
+	require.Len(t, thErr.Violations, 3)
+	require.Equal(t, 75.0, thErr.Violations[0].Coverage)
+	require.Equal(t, 50.0, thErr.Violations[1].Coverage)
+	require.Equal(t, 0.0, thErr.Violations[2].Coverage)
+	require.Contains(t, err.Error(), "coverage by function 75.00% is below 80.00%")
+
+	err = report.CheckThresholds(coverage.Threshold{Scope: coverage.ThresholdScopePattern, Target: "(", Min: 1})
+	require.ErrorIs(t, err, coverage.ErrThresholdInvalid)
+}
+
+func TestCheckThresholds_Package(t *testing.T) {
+	report := coverage.NewCoverageReport(
+		coverage.WithReportFuncsTraced([]string{"main.fooFunction", "main.barFunction"}),
+		coverage.WithReportFuncsAck([]string{"main.fooFunction"}),
+		coverage.WithReportExePath(testBinary),
+	)
+
+	require.NoError(t, report.CheckThresholds(coverage.Threshold{Scope: coverage.ThresholdScopePackage, Target: "main", Min: 50}))
+
+	var thErr *coverage.ThresholdError
+	err := report.CheckThresholds(coverage.Threshold{Scope: coverage.ThresholdScopePackage, Target: "main", Min: 51})
+	require.ErrorAs(t, err, &thErr)
+	require.Equal(t, 50.0, thErr.Violations[0].Coverage)
+}
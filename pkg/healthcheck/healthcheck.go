@@ -2,39 +2,73 @@ package healthcheck
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net"
 	"os"
 	"syscall"
-	"time"
 
 	"github.com/pkg/errors"
 
 	log "github.com/rs/zerolog"
+
+	"github.com/maxgio92/xcover/pkg/coverage"
 )
 
-const ReadyMsg = 0x01
+var ErrSocketInUse = errors.New("socket in use by another server")
+
+// Handler handles the requests to the tracer, once it is ready.
+type Handler interface {
+	Status() (*Status, error)
+	Coverage() (*coverage.CoverageReport, error)
+	Snapshot(format coverage.ReportFormat, path string) error
+	Reset(format coverage.ReportFormat, path string) error
+	Mark(label string) error
+	Shutdown() error
+}
 
 type HealthCheckServer struct {
 	ln         net.Listener
 	readyCh    chan struct{}
 	socketPath string
+	handler    Handler
 	logger     log.Logger
 }
 
+type Option func(*HealthCheckServer)
+
+// WithHandler sets the handler of the requests other than the readiness ones.
+func WithHandler(handler Handler) Option {
+	return func(s *HealthCheckServer) {
+		s.handler = handler
+	}
+}
+
 // NewHealthCheckServer creates a new health check server.
-func NewHealthCheckServer(socketPath string, logger log.Logger) *HealthCheckServer {
+func NewHealthCheckServer(socketPath string, logger log.Logger, opts ...Option) *HealthCheckServer {
 	l := logger.With().Str("component", "healthcheck").Logger()
-	return &HealthCheckServer{
+	s := &HealthCheckServer{
 		socketPath: socketPath,
 		readyCh:    make(chan struct{}),
 		logger:     l,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
 // InitializeListener starts the UDS listener for accepting connections.
 func (s *HealthCheckServer) InitializeListener(ctx context.Context) error {
+	// Do not take over the socket of a server still listening, like the
+	// profiler of the same session.
+	if conn, err := net.Dial("unix", s.socketPath); err == nil {
+		conn.Close()
+		return errors.Wrap(ErrSocketInUse, s.socketPath)
+	}
+
 	// Remove socket if it already exists.
 	os.Remove(s.socketPath)
 
@@ -58,6 +92,11 @@ func (s *HealthCheckServer) NotifyReadiness() {
 	close(s.readyCh)
 }
 
+// Ready returns a channel that is closed when the readiness is notified.
+func (s *HealthCheckServer) Ready() <-chan struct{} {
+	return s.readyCh
+}
+
 // ShutdownListener gracefully shuts down the listener and removes the socket.
 func (s *HealthCheckServer) ShutdownListener() error {
 	// Ensure the listener is closed properly.
@@ -104,46 +143,122 @@ func (s *HealthCheckServer) acceptConnections(ctx context.Context) {
 	}
 }
 
-// processConnection handles each accepted connection and responds when ready.
+// processConnection handles the requests of each accepted connection,
+// until the peer closes it.
 func (s *HealthCheckServer) processConnection(ctx context.Context, conn net.Conn) {
 	defer conn.Close()
 
-	select {
-	// Tracer is ready, send ready message.
-	case <-s.readyCh:
-		// Test that the connection is still open.
-		if !s.isConnectionAlive(conn) {
-			s.logger.Debug().Msg("connection is closed")
+	for {
+		var req Request
+		if err := ReadMessage(conn, &req); err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				s.logger.Debug().Err(err).Msg("failed to read request")
+			}
 			return
 		}
-		if err := s.safeWrite(conn, []byte{ReadyMsg}); err != nil {
+
+		if err := s.safeWrite(conn, s.handleRequest(ctx, &req)); err != nil {
 			if !errors.Is(err, syscall.EPIPE) && !errors.Is(err, syscall.ECONNRESET) {
 				s.logger.Debug().Err(err).Msg("failed to write")
 			}
+			return
 		}
-	case <-ctx.Done():
-		// Graceful shutdown handling.
-		s.logger.Debug().Msg("ignoring sending readiness message as context is canceled")
-		return
 	}
 }
 
-func (s *HealthCheckServer) isConnectionAlive(conn net.Conn) bool {
-	// Decrease timeout to read fast.
-	conn.SetReadDeadline(time.Now())
-	if _, err := conn.Read([]byte{}); err == io.EOF {
-		s.logger.Debug().Err(err).Msg("cannot write ready message: connection is already closed")
-		conn.Close()
+// handleRequest returns the response to the request.
+func (s *HealthCheckServer) handleRequest(ctx context.Context, req *Request) *Response {
+	resp := &Response{Version: ProtocolVersion}
+
+	result, err := s.dispatch(ctx, req)
+	if err != nil {
+		s.logger.Debug().Err(err).Str("method", string(req.Method)).Msg("request failed")
+		resp.Error = err.Error()
+		return resp
+	}
+	if result != nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			resp.Error = errors.Wrap(err, "failed to encode result").Error()
+			return resp
+		}
+		resp.Result = data
+	}
+
+	return resp
+}
 
-		return false
+func (s *HealthCheckServer) dispatch(ctx context.Context, req *Request) (any, error) {
+	if req.Version != ProtocolVersion {
+		return nil, errors.Wrapf(ErrVersionUnsupported, "%d", req.Version)
+	}
+
+	// Wait for the readiness.
+	if req.Method == MethodReady {
+		select {
+		case <-s.readyCh:
+			return &ReadyResult{Ready: true}, nil
+		case <-ctx.Done():
+			return nil, ErrNotReady
+		}
+	}
+
+	// The other requests are handled only when ready.
+	select {
+	case <-s.readyCh:
+	default:
+		return nil, ErrNotReady
+	}
+	if s.handler == nil {
+		return nil, errors.Wrap(ErrMethodUnknown, string(req.Method))
 	}
 
-	conn.SetReadDeadline(time.Time{})
-	return true
+	switch req.Method {
+	case MethodStatus:
+		return s.handler.Status()
+	case MethodCoverage:
+		return s.handler.Coverage()
+	case MethodSnapshot:
+		var params ReportParams
+		if err := decodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		if params.Path == "" {
+			return nil, ErrReportPathEmpty
+		}
+		return nil, s.handler.Snapshot(params.Format, params.Path)
+	case MethodReset:
+		var params ReportParams
+		if err := decodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.handler.Reset(params.Format, params.Path)
+	case MethodMark:
+		var params MarkParams
+		if err := decodeParams(req, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.handler.Mark(params.Label)
+	case MethodShutdown:
+		return nil, s.handler.Shutdown()
+	default:
+		return nil, errors.Wrap(ErrMethodUnknown, string(req.Method))
+	}
+}
+
+func decodeParams(req *Request, params any) error {
+	if len(req.Params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(req.Params, params); err != nil {
+		return errors.Wrapf(err, "invalid params of %s request", req.Method)
+	}
+
+	return nil
 }
 
-func (s *HealthCheckServer) safeWrite(conn net.Conn, data []byte) error {
-	_, err := conn.Write(data)
+func (s *HealthCheckServer) safeWrite(conn net.Conn, msg any) error {
+	err := WriteMessage(conn, msg)
 	if err != nil {
 		switch {
 		case errors.Is(err, syscall.EPIPE):
@@ -153,7 +268,7 @@ func (s *HealthCheckServer) safeWrite(conn net.Conn, data []byte) error {
 			conn.Close()
 			return errors.Wrap(err, "peer reset the connection")
 		default:
-			return errors.Wrap(err, "failed to write")
+			return err
 		}
 	}
 	return nil
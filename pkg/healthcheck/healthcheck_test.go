@@ -2,6 +2,7 @@ package healthcheck
 
 import (
 	"context"
+	"encoding/json"
 	"net"
 	"os"
 	"testing"
@@ -9,69 +10,39 @@ import (
 
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
-)
-
-// MockConn implements the net.Conn interface for testing purposes
-type MockConn struct {
-	mock.Mock
-}
-
-// Implementing the net.Conn interface methods
-
-func (m *MockConn) Read(b []byte) (n int, err error) {
-	args := m.Called(b)
-	return args.Int(0), args.Error(1)
-}
-
-func (m *MockConn) Write(b []byte) (n int, err error) {
-	args := m.Called(b)
-	return args.Int(0), args.Error(1)
-}
-
-func (m *MockConn) Close() error {
-	args := m.Called()
-	return args.Error(0)
-}
-
-func (m *MockConn) LocalAddr() net.Addr {
-	args := m.Called()
-	return args.Get(0).(net.Addr)
-}
-
-func (m *MockConn) RemoteAddr() net.Addr {
-	args := m.Called()
-	return args.Get(0).(net.Addr)
-}
-
-func (m *MockConn) SetDeadline(t time.Time) error {
-	args := m.Called(t)
-	return args.Error(0)
-}
-
-func (m *MockConn) SetReadDeadline(t time.Time) error {
-	args := m.Called(t)
-	return args.Error(0)
-}
+	"github.com/stretchr/testify/require"
 
-func (m *MockConn) SetWriteDeadline(t time.Time) error {
-	args := m.Called(t)
-	return args.Error(0)
-}
+	"github.com/maxgio92/xcover/pkg/coverage"
+)
 
 func TestHealthCheckServer_InitializeListener(t *testing.T) {
 	t.Run("should start UDS listener without errors", func(t *testing.T) {
 		logger := zerolog.New(zerolog.NewTestWriter(t)).With().Timestamp().Logger()
 		hcs := NewHealthCheckServer("/tmp/server.sock", logger)
 
+		// Leave a stale socket.
 		os.Remove("/tmp/server.sock")
 		ln, err := net.Listen("unix", "/tmp/server.sock")
 		assert.Nil(t, err)
-		hcs.ln = ln
+		ln.(*net.UnixListener).SetUnlinkOnClose(false)
+		ln.Close()
 
 		err = hcs.InitializeListener(context.Background())
 		assert.Nil(t, err)
 	})
+
+	t.Run("should not take over the socket of a server listening", func(t *testing.T) {
+		logger := zerolog.New(zerolog.NewTestWriter(t)).With().Timestamp().Logger()
+		hcs := NewHealthCheckServer("/tmp/server.sock", logger)
+
+		os.Remove("/tmp/server.sock")
+		ln, err := net.Listen("unix", "/tmp/server.sock")
+		require.NoError(t, err)
+		defer ln.Close()
+
+		err = hcs.InitializeListener(context.Background())
+		require.ErrorIs(t, err, ErrSocketInUse)
+	})
 }
 
 func TestHealthCheckServer_NotifyReadiness(t *testing.T) {
@@ -87,19 +58,14 @@ func TestHealthCheckServer_NotifyReadiness(t *testing.T) {
 			hcs.readyCh <- struct{}{}
 		})
 
-		// Mock connection.
-		mockConn := new(MockConn)
-
-		// Verify the readiness message was sent.
-		mockConn.On("Write", []byte{ReadyMsg}).Return(len([]byte{ReadyMsg}), nil)
-		mockConn.On("Close").Return(nil)
-		mockConn.On("SetReadDeadline", mock.Anything).Return(nil)
-		//mockConn.On("Read", mock.AnythingOfType("[]uint8")).Return(0, io.EOF)
-		mockConn.On("Read", mock.AnythingOfType("[]uint8")).Return(1, nil)
-
-		hcs.processConnection(context.Background(), mockConn)
+		// Verify the readiness response is sent.
+		server, conn := net.Pipe()
+		defer conn.Close()
+		go hcs.processConnection(context.Background(), server)
 
-		mockConn.AssertExpectations(t)
+		resp := request(t, conn, &Request{Version: ProtocolVersion, Method: MethodReady})
+		assert.Empty(t, resp.Error)
+		assert.JSONEq(t, `{"ready":true}`, string(resp.Result))
 	})
 }
 
@@ -127,3 +93,118 @@ func TestHealthCheckServer_ShutdownListener(t *testing.T) {
 		assert.ErrorIs(t, err, os.ErrNotExist)
 	})
 }
+
+func TestHealthCheckServer_Ready(t *testing.T) {
+	server := NewHealthCheckServer("/tmp/test-ready.sock", zerolog.Nop())
+
+	select {
+	case <-server.Ready():
+		t.Fatal("ready before notifying readiness")
+	default:
+	}
+
+	server.NotifyReadiness()
+
+	select {
+	case <-server.Ready():
+	case <-time.After(time.Second):
+		t.Fatal("not ready after notifying readiness")
+	}
+}
+
+type handler struct {
+	labels   []string
+	shutdown bool
+}
+
+func (h *handler) Status() (*Status, error) {
+	return &Status{Pid: 1234, FuncsTraced: 4, FuncsAck: 1, CovByFunc: 25}, nil
+}
+
+func (h *handler) Coverage() (*coverage.CoverageReport, error) {
+	return coverage.NewCoverageReport(coverage.WithReportFuncsAck([]string{"foo"})), nil
+}
+
+func (h *handler) Snapshot(_ coverage.ReportFormat, _ string) error {
+	return nil
+}
+
+func (h *handler) Reset(_ coverage.ReportFormat, _ string) error {
+	return nil
+}
+
+func (h *handler) Mark(label string) error {
+	h.labels = append(h.labels, label)
+	return nil
+}
+
+func (h *handler) Shutdown() error {
+	h.shutdown = true
+	return nil
+}
+
+func request(t *testing.T, conn net.Conn, req *Request) *Response {
+	t.Helper()
+
+	require.NoError(t, WriteMessage(conn, req))
+	resp := new(Response)
+	require.NoError(t, ReadMessage(conn, resp))
+	require.Equal(t, ProtocolVersion, resp.Version)
+
+	return resp
+}
+
+func TestHealthCheckServer_HandleRequests(t *testing.T) {
+	h := new(handler)
+	hcs := NewHealthCheckServer("/tmp/test-requests.sock", zerolog.Nop(), WithHandler(h))
+
+	server, conn := net.Pipe()
+	defer conn.Close()
+	go hcs.processConnection(context.Background(), server)
+
+	// Not ready yet.
+	resp := request(t, conn, &Request{Version: ProtocolVersion, Method: MethodStatus})
+	require.Equal(t, ErrNotReady.Error(), resp.Error)
+
+	hcs.NotifyReadiness()
+
+	resp = request(t, conn, &Request{Version: ProtocolVersion, Method: MethodStatus})
+	require.Empty(t, resp.Error)
+	var status Status
+	require.NoError(t, json.Unmarshal(resp.Result, &status))
+	require.Equal(t, 1234, status.Pid)
+	require.Equal(t, 25.0, status.CovByFunc)
+
+	resp = request(t, conn, &Request{Version: ProtocolVersion, Method: MethodCoverage})
+	require.Empty(t, resp.Error)
+	var report coverage.CoverageReport
+	require.NoError(t, json.Unmarshal(resp.Result, &report))
+	require.Equal(t, []string{"foo"}, report.FuncsAck)
+
+	resp = request(t, conn, &Request{Version: ProtocolVersion, Method: MethodMark, Params: json.RawMessage(`{"label":"test1"}`)})
+	require.Empty(t, resp.Error)
+	require.Equal(t, []string{"test1"}, h.labels)
+
+	resp = request(t, conn, &Request{Version: ProtocolVersion, Method: MethodSnapshot, Params: json.RawMessage(`{"format":"json"}`)})
+	require.Equal(t, ErrReportPathEmpty.Error(), resp.Error)
+
+	resp = request(t, conn, &Request{Version: ProtocolVersion, Method: MethodShutdown})
+	require.Empty(t, resp.Error)
+	require.True(t, h.shutdown)
+
+	resp = request(t, conn, &Request{Version: ProtocolVersion, Method: "unknown"})
+	require.Contains(t, resp.Error, ErrMethodUnknown.Error())
+
+	resp = request(t, conn, &Request{Version: ProtocolVersion + 1, Method: MethodStatus})
+	require.Contains(t, resp.Error, ErrVersionUnsupported.Error())
+}
+
+func TestReadMessage_TooLarge(t *testing.T) {
+	server, conn := net.Pipe()
+	defer conn.Close()
+
+	go server.Write([]byte{0xff, 0xff, 0xff, 0xff})
+
+	var req Request
+	require.ErrorIs(t, ReadMessage(conn, &req), ErrMessageTooLarge)
+}
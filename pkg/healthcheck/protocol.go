@@ -0,0 +1,144 @@
+package healthcheck
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/maxgio92/xcover/pkg/coverage"
+)
+
+// ProtocolVersion is the version of the protocol of the requests and
+// responses exchanged over the socket.
+// Each message is a JSON object prefixed by its length, as a big endian
+// 32-bit unsigned integer.
+const ProtocolVersion = 1
+
+// MaxMessageSize is the maximum size of a message, that can be a report.
+const MaxMessageSize = 256 << 20
+
+// Method is the method of a request.
+type Method string
+
+const (
+	// MethodReady waits for the tracer to be ready.
+	MethodReady Method = "ready"
+	// MethodStatus returns the status of the tracer.
+	MethodStatus Method = "status"
+	// MethodCoverage returns the current coverage report.
+	MethodCoverage Method = "coverage"
+	// MethodSnapshot writes the current coverage report to a file.
+	MethodSnapshot Method = "snapshot"
+	// MethodReset resets the coverage, optionally writing the report before.
+	MethodReset Method = "reset"
+	// MethodMark marks the label of the functions called from now on.
+	MethodMark Method = "mark"
+	// MethodShutdown stops the tracer, that writes the report.
+	MethodShutdown Method = "shutdown"
+)
+
+var (
+	ErrVersionUnsupported = errors.New("unsupported protocol version")
+	ErrMethodUnknown      = errors.New("unknown method")
+	ErrMessageTooLarge    = errors.New("message too large")
+	ErrNotReady           = errors.New("tracer is not ready")
+	ErrReportPathEmpty    = errors.New("report path is empty")
+)
+
+// Request is a request to the tracer.
+type Request struct {
+	Version int             `json:"version"`
+	Method  Method          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is the response to a request, with either the result or the error.
+type Response struct {
+	Version int             `json:"version"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// ReportParams are the parameters of the requests writing a report.
+type ReportParams struct {
+	Format coverage.ReportFormat `json:"format"`
+	Path   string                `json:"path"`
+}
+
+// MarkParams are the parameters of the mark requests.
+type MarkParams struct {
+	Label string `json:"label"`
+}
+
+// ReadyResult is the result of the ready requests.
+type ReadyResult struct {
+	Ready bool `json:"ready"`
+}
+
+// Status is the status of the tracer.
+type Status struct {
+	Pid     int    `json:"pid"`
+	ExePath string `json:"exe_path"`
+	// ExePaths are the paths of all the executables traced, the first
+	// being ExePath.
+	ExePaths      []string `json:"exe_paths,omitempty"`
+	FuncsTraced   int      `json:"funcs_traced"`
+	FuncsAttached uint64   `json:"funcs_attached"`
+	FuncsFailed   uint64   `json:"funcs_failed"`
+	FuncsAck      int      `json:"funcs_ack"`
+	CovByFunc     float64  `json:"cov_by_func"`
+	// EventsConsumed is the number of function events consumed.
+	EventsConsumed uint64 `json:"events_consumed"`
+	// EventBufUtil is the percentage of the events ring buffer not yet consumed.
+	EventBufUtil float64 `json:"event_buf_util"`
+	// Uptime is the time since tracing started, in nanoseconds.
+	Uptime time.Duration `json:"uptime"`
+}
+
+// WriteMessage writes the message as length-prefixed JSON to w.
+func WriteMessage(w io.Writer, msg any) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode message")
+	}
+	if len(data) > MaxMessageSize {
+		return ErrMessageTooLarge
+	}
+
+	buf := make([]byte, 4+len(data))
+	binary.BigEndian.PutUint32(buf, uint32(len(data)))
+	copy(buf[4:], data)
+	if _, err := w.Write(buf); err != nil {
+		return errors.Wrap(err, "failed to write message")
+	}
+
+	return nil
+}
+
+// ReadMessage reads a length-prefixed JSON message from r into msg.
+// It returns io.EOF when r is closed before a message.
+func ReadMessage(r io.Reader, msg any) error {
+	var size uint32
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		if errors.Is(err, io.EOF) {
+			return io.EOF
+		}
+		return errors.Wrap(err, "failed to read message size")
+	}
+	if size > MaxMessageSize {
+		return ErrMessageTooLarge
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return errors.Wrap(err, "failed to read message")
+	}
+	if err := json.Unmarshal(data, msg); err != nil {
+		return errors.Wrap(err, "failed to decode message")
+	}
+
+	return nil
+}
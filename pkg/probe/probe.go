@@ -3,7 +3,12 @@ package probe
 import (
 	"context"
 	"embed"
+	"encoding/binary"
+	"os"
 	"path/filepath"
+	"sync/atomic"
+	"syscall"
+	"unsafe"
 
 	bpf "github.com/maxgio92/libbpfgo"
 	"github.com/pkg/errors"
@@ -19,7 +24,20 @@ const (
 	ProgName              = "handle_user_function"
 	EventsChBufSize       = 4096
 	evtRingBufBPFMapName  = "events"
+	evtRingBufSize        = 1 << 28 // As the events ring buffer max entries.
 	evtRingBufPollTimeout = 60
+	targetPidVarName      = "target_pid"
+	countHitsVarName      = "count_hits"
+	hitsBPFMapName        = "func_hits"
+	seenBPFMapName        = "seen_funcs"
+	filterTreeVarName     = "filter_tree"
+	tracedPidsBPFMapName  = "traced_pids"
+	filterCgroupVarName   = "filter_cgroup"
+	cgroupBPFMapName      = "target_cgroup"
+	labelBPFMapName       = "current_label"
+	genBPFMapName         = "reset_gen"
+	forkProgName          = "handle_process_fork"
+	exitProgName          = "handle_process_exit"
 )
 
 type Probe struct {
@@ -31,6 +49,16 @@ type Probe struct {
 
 	EvtBuf *bpf.RingBuffer
 
+	// pid filters the traced process, -1 means all processes.
+	pid int
+	// hits enables counting the function hits.
+	hits bool
+	// tree filters the traced process trees, added with AddTracedPid.
+	tree bool
+	// cgroup filters the tasks in the cgroup v2 hierarchy at the path, empty
+	// means all cgroups.
+	cgroup string
+
 	logger log.Logger
 }
 
@@ -42,8 +70,43 @@ func WithLogger(logger log.Logger) Option {
 	}
 }
 
+// WithPid filters the traced process. Any pid not positive means all
+// processes, as the uprobes attached with pid 0 would trace the calling
+// process only.
+func WithPid(pid int) Option {
+	return func(p *Probe) {
+		if pid <= 0 {
+			pid = -1
+		}
+		p.pid = pid
+	}
+}
+
+func WithHits(hits bool) Option {
+	return func(p *Probe) {
+		p.hits = hits
+	}
+}
+
+func WithProcessTree(tree bool) Option {
+	return func(p *Probe) {
+		p.tree = tree
+	}
+}
+
+func WithCgroup(path string) Option {
+	return func(p *Probe) {
+		p.cgroup = path
+	}
+}
+
 func NewProbe(opts ...Option) *Probe {
-	return new(Probe)
+	p := &Probe{pid: -1}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
 }
 
 func (p *Probe) read(path string) ([]byte, error) {
@@ -83,10 +146,118 @@ func (p *Probe) Init(_ context.Context) error {
 		return errors.Wrapf(err, "failed to set expected attach type %s", bpf.BPFAttachTypeTraceUprobeMulti)
 	}
 
+	if p.pid > 0 {
+		if err := p.bpfMod.InitGlobalVariable(targetPidVarName, uint32(p.pid)); err != nil {
+			return errors.Wrapf(err, "failed to set %s", targetPidVarName)
+		}
+	}
+
+	if p.hits {
+		if err := p.bpfMod.InitGlobalVariable(countHitsVarName, p.hits); err != nil {
+			return errors.Wrapf(err, "failed to set %s", countHitsVarName)
+		}
+	}
+
+	if p.tree {
+		if err := p.bpfMod.InitGlobalVariable(filterTreeVarName, p.tree); err != nil {
+			return errors.Wrapf(err, "failed to set %s", filterTreeVarName)
+		}
+	}
+
+	if p.cgroup != "" {
+		if err := p.bpfMod.InitGlobalVariable(filterCgroupVarName, true); err != nil {
+			return errors.Wrapf(err, "failed to set %s", filterCgroupVarName)
+		}
+	}
+
 	if err := p.bpfMod.BPFLoadObject(); err != nil {
 		return errors.Wrapf(err, "failed to load bpf module %s", p.Name)
 	}
 
+	if p.cgroup != "" {
+		if err := p.setCgroup(); err != nil {
+			return err
+		}
+	}
+
+	if p.tree {
+		if err := p.attachProcessTree(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// attachProcessTree attaches the programs that track the children of the
+// traced processes.
+func (p *Probe) attachProcessTree() error {
+	for _, name := range []string{forkProgName, exitProgName} {
+		prog, err := p.bpfMod.GetProgram(name)
+		if err != nil {
+			return errors.Wrapf(err, "failed to get bpf program: %s", name)
+		}
+		if _, err := prog.AttachGeneric(); err != nil {
+			return errors.Wrapf(err, "failed to attach bpf program: %s", name)
+		}
+	}
+
+	return nil
+}
+
+// setCgroup stores the cgroup to filter in the map, that holds a reference
+// to it, so the cgroup directory can be closed afterwards.
+func (p *Probe) setCgroup() error {
+	cgroupMap, err := p.bpfMod.GetMap(cgroupBPFMapName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get bpf map %s", cgroupBPFMapName)
+	}
+
+	f, err := os.Open(p.cgroup)
+	if err != nil {
+		return errors.Wrapf(err, "failed to open cgroup %s", p.cgroup)
+	}
+	defer f.Close()
+
+	key := uint32(0)
+	fd := uint32(f.Fd())
+	if err := cgroupMap.Update(unsafe.Pointer(&key), unsafe.Pointer(&fd)); err != nil {
+		return errors.Wrapf(err, "failed to set cgroup %s in bpf map %s", p.cgroup, cgroupBPFMapName)
+	}
+
+	return nil
+}
+
+// AddTracedPid adds the process to the traced process trees, along with the
+// children it creates from now on.
+func (p *Probe) AddTracedPid(pid int) error {
+	pidsMap, err := p.bpfMod.GetMap(tracedPidsBPFMapName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get bpf map %s", tracedPidsBPFMapName)
+	}
+
+	key := uint32(pid)
+	value := uint8(1)
+	if err := pidsMap.Update(unsafe.Pointer(&key), unsafe.Pointer(&value)); err != nil {
+		return errors.Wrapf(err, "failed to add pid %d to bpf map %s", pid, tracedPidsBPFMapName)
+	}
+
+	return nil
+}
+
+// RemoveTracedPid removes the process from the traced process trees.
+// The children it created meanwhile are still traced.
+func (p *Probe) RemoveTracedPid(pid int) error {
+	pidsMap, err := p.bpfMod.GetMap(tracedPidsBPFMapName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get bpf map %s", tracedPidsBPFMapName)
+	}
+
+	key := uint32(pid)
+	if err := pidsMap.DeleteKey(unsafe.Pointer(&key)); err != nil {
+		return errors.Wrapf(err, "failed to remove pid %d from bpf map %s", pid, tracedPidsBPFMapName)
+	}
+
 	return nil
 }
 
@@ -102,9 +273,126 @@ func (p *Probe) configureBPFLogger() {
 }
 
 func (p *Probe) Attach(_ context.Context, exePath string, offsets, cookies []uint64) error {
-	if _, err := p.bpfProg.AttachUprobeMulti(-1, exePath, offsets, cookies); err != nil {
-		p.logger.Warn().Err(errors.Wrapf(err, "error attaching uprobe for functions with cookies: %v", cookies))
+	if _, err := p.bpfProg.AttachUprobeMulti(p.pid, exePath, offsets, cookies); err != nil {
+		return errors.Wrapf(err, "error attaching uprobe for functions with cookies: %v", cookies)
+	}
+	return nil
+}
+
+// GetEventBufUtilization returns the percentage of the events ring buffer
+// not yet consumed, from the positions of the consumer and the producer.
+func (p *Probe) GetEventBufUtilization() (float64, error) {
+	evtMap, err := p.bpfMod.GetMap(evtRingBufBPFMapName)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to get bpf map %s", evtRingBufBPFMapName)
+	}
+
+	// The consumer and the producer positions are at the start of the
+	// first two pages of the ring buffer map.
+	pageSize := os.Getpagesize()
+	pages, err := syscall.Mmap(evtMap.FileDescriptor(), 0, 2*pageSize, syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to map the positions of bpf map %s", evtRingBufBPFMapName)
+	}
+	defer syscall.Munmap(pages)
+
+	consumer := atomic.LoadUint64((*uint64)(unsafe.Pointer(&pages[0])))
+	producer := atomic.LoadUint64((*uint64)(unsafe.Pointer(&pages[pageSize])))
+
+	return float64(producer-consumer) / evtRingBufSize * 100, nil
+}
+
+// SetLabel sets the label the functions called from now on are reported
+// with, 0 meaning none. Each function is reported once per label.
+func (p *Probe) SetLabel(label uint32) error {
+	labelMap, err := p.bpfMod.GetMap(labelBPFMapName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get bpf map %s", labelBPFMapName)
+	}
+
+	key := uint32(0)
+	if err := labelMap.Update(unsafe.Pointer(&key), unsafe.Pointer(&label)); err != nil {
+		return errors.Wrapf(err, "failed to set label %d in bpf map %s", label, labelBPFMapName)
+	}
+
+	return nil
+}
+
+// SetGeneration sets the reset generation the functions called from now on
+// are reported with, to tell the events reported before a reset.
+func (p *Probe) SetGeneration(gen uint32) error {
+	genMap, err := p.bpfMod.GetMap(genBPFMapName)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get bpf map %s", genBPFMapName)
+	}
+
+	key := uint32(0)
+	if err := genMap.Update(unsafe.Pointer(&key), unsafe.Pointer(&gen)); err != nil {
+		return errors.Wrapf(err, "failed to set generation %d in bpf map %s", gen, genBPFMapName)
+	}
+
+	return nil
+}
+
+// GetHits returns the number of hits counted for each function cookie.
+func (p *Probe) GetHits() (map[uint64]uint64, error) {
+	hitsMap, err := p.bpfMod.GetMap(hitsBPFMapName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get bpf map %s", hitsBPFMapName)
+	}
+
+	hits := make(map[uint64]uint64)
+	iter := hitsMap.Iterator()
+	for iter.Next() {
+		key := iter.Key()
+		value, err := hitsMap.GetValue(unsafe.Pointer(&key[0]))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get hits from bpf map %s", hitsBPFMapName)
+		}
+		hits[binary.LittleEndian.Uint64(key)] = binary.LittleEndian.Uint64(value)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed to iterate bpf map %s", hitsBPFMapName)
+	}
+
+	return hits, nil
+}
+
+// Reset clears the functions reported and their hits, so that they are
+// reported again when called from now on.
+func (p *Probe) Reset() error {
+	for _, name := range []string{seenBPFMapName, hitsBPFMapName} {
+		if err := p.clearMap(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// clearMap deletes all the entries of the map.
+func (p *Probe) clearMap(name string) error {
+	m, err := p.bpfMod.GetMap(name)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get bpf map %s", name)
+	}
+
+	// Collect the keys first, as deleting while iterating restarts the iteration.
+	var keys [][]byte
+	iter := m.Iterator()
+	for iter.Next() {
+		keys = append(keys, iter.Key())
+	}
+	if err := iter.Err(); err != nil {
+		return errors.Wrapf(err, "failed to iterate bpf map %s", name)
 	}
+
+	for _, key := range keys {
+		if err := m.DeleteKey(unsafe.Pointer(&key[0])); err != nil {
+			return errors.Wrapf(err, "failed to delete from bpf map %s", name)
+		}
+	}
+
 	return nil
 }
 
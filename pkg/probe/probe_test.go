@@ -0,0 +1,34 @@
+package probe
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewProbe_Pid(t *testing.T) {
+	p := NewProbe()
+	require.Equal(t, -1, p.pid)
+
+	p = NewProbe(WithPid(1234))
+	require.Equal(t, 1234, p.pid)
+
+	p = NewProbe(WithPid(0))
+	require.Equal(t, -1, p.pid)
+}
+
+func TestNewProbe_ProcessTree(t *testing.T) {
+	p := NewProbe()
+	require.False(t, p.tree)
+
+	p = NewProbe(WithProcessTree(true))
+	require.True(t, p.tree)
+}
+
+func TestNewProbe_Cgroup(t *testing.T) {
+	p := NewProbe()
+	require.Empty(t, p.cgroup)
+
+	p = NewProbe(WithCgroup("/sys/fs/cgroup/system.slice"))
+	require.Equal(t, "/sys/fs/cgroup/system.slice", p.cgroup)
+}
@@ -0,0 +1,90 @@
+package procfs
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	procDir       = "/proc"
+	deletedSuffix = " (deleted)"
+	// mapsFields is the number of fields of a /proc/PID/maps line
+	// for file-backed mappings.
+	mapsFields = 6
+)
+
+// GetPids returns the PIDs of the running processes of the executable.
+func GetPids(exePath string) ([]int, error) {
+	exeInfo, err := os.Stat(exePath)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read %s", procDir)
+	}
+
+	var pids []int
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		// The processes can exit meanwhile or not be accessible.
+		info, err := os.Stat(filepath.Join(procDir, entry.Name(), "exe"))
+		if err != nil {
+			continue
+		}
+		if os.SameFile(exeInfo, info) {
+			pids = append(pids, pid)
+		}
+	}
+
+	return pids, nil
+}
+
+// GetExecMappings returns the paths of the files mapped as executable
+// in the address space of the process.
+func GetExecMappings(pid int) ([]string, error) {
+	f, err := os.Open(filepath.Join(procDir, strconv.Itoa(pid), "maps"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var paths []string
+	seen := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// address perms offset dev inode pathname
+		fields := strings.SplitN(scanner.Text(), " ", mapsFields)
+		if len(fields) < mapsFields {
+			continue
+		}
+		perms := fields[1]
+		path := strings.TrimSpace(fields[mapsFields-1])
+		if len(perms) < 3 || perms[2] != 'x' {
+			continue
+		}
+		// Skip anonymous, special and deleted mappings.
+		if !strings.HasPrefix(path, "/") || strings.HasSuffix(path, deletedSuffix) {
+			continue
+		}
+		if _, ok := seen[path]; ok {
+			continue
+		}
+		seen[path] = struct{}{}
+		paths = append(paths, path)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrapf(err, "failed to read maps of process %d", pid)
+	}
+
+	return paths, nil
+}
@@ -0,0 +1,34 @@
+package procfs_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxgio92/xcover/pkg/procfs"
+)
+
+func TestGetPids(t *testing.T) {
+	exe, err := os.Executable()
+	require.NoError(t, err)
+
+	pids, err := procfs.GetPids(exe)
+	require.NoError(t, err)
+	require.Contains(t, pids, os.Getpid())
+
+	_, err = procfs.GetPids("nonexistent-binary-file")
+	require.Error(t, err)
+}
+
+func TestGetExecMappings(t *testing.T) {
+	exe, err := os.Executable()
+	require.NoError(t, err)
+	exe, err = filepath.EvalSymlinks(exe)
+	require.NoError(t, err)
+
+	paths, err := procfs.GetExecMappings(os.Getpid())
+	require.NoError(t, err)
+	require.Contains(t, paths, exe)
+}
@@ -0,0 +1,64 @@
+package static
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	gnuBuildIDSection = ".note.gnu.build-id"
+	goBuildIDSection  = ".note.go.buildid"
+	noteHeaderSize    = 12
+)
+
+var ErrBuildIDNotFound = errors.New("build ID not found")
+
+// GetBuildID returns the build ID of the ELF file, that is the GNU build ID
+// when present, otherwise the Go build ID.
+func GetBuildID(name string) (string, error) {
+	f, err := elf.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if desc, err := readNoteDesc(f, gnuBuildIDSection); err == nil {
+		return hex.EncodeToString(desc), nil
+	}
+	if desc, err := readNoteDesc(f, goBuildIDSection); err == nil {
+		return string(bytes.TrimRight(desc, "\x00")), nil
+	}
+
+	return "", ErrBuildIDNotFound
+}
+
+// readNoteDesc returns the descriptor of the first note in the section.
+func readNoteDesc(f *elf.File, section string) ([]byte, error) {
+	s := f.Section(section)
+	if s == nil {
+		return nil, ErrBuildIDNotFound
+	}
+	data, err := s.Data()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < noteHeaderSize {
+		return nil, ErrBuildIDNotFound
+	}
+
+	nameSize := f.ByteOrder.Uint32(data[0:4])
+	descSize := f.ByteOrder.Uint32(data[4:8])
+	descOff := noteHeaderSize + align4(nameSize)
+	if uint64(descOff)+uint64(descSize) > uint64(len(data)) {
+		return nil, ErrBuildIDNotFound
+	}
+
+	return data[descOff : descOff+descSize], nil
+}
+
+func align4(n uint32) uint32 {
+	return (n + 3) &^ 3
+}
@@ -0,0 +1,18 @@
+package static_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxgio92/xcover/pkg/static"
+)
+
+func TestGetBuildID(t *testing.T) {
+	buildID, err := static.GetBuildID(testBinary)
+	require.NoError(t, err)
+	require.NotEmpty(t, buildID)
+
+	_, err = static.GetBuildID("nonexistent-binary-file")
+	require.Error(t, err)
+}
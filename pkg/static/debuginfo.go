@@ -0,0 +1,140 @@
+package static
+
+import (
+	"bytes"
+	"debug/elf"
+	"encoding/hex"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	debugLinkSection   = ".gnu_debuglink"
+	symbolTableSection = ".symtab"
+	buildIDDir         = ".build-id"
+	debugFileExt       = ".debug"
+)
+
+var (
+	// DebugDirs are the global directories where separate debug info files are looked up.
+	DebugDirs = []string{"/usr/lib/debug"}
+
+	ErrDebugFileNotFound       = errors.New("debug file not found")
+	ErrSymbolNotInExecSegments = errors.New("symbol not found in executable segments")
+)
+
+// HasSymbols returns whether the ELF file has a symbol table.
+func HasSymbols(f *elf.File) bool {
+	return f.Section(symbolTableSection) != nil
+}
+
+// FindDebugFile returns the path of the separate debug info file of the ELF
+// file, looked up by GNU build ID and by debug link, like GDB does:
+//   - /usr/lib/debug/.build-id/xx/yyyy.debug
+//   - the debug link in the directory of the ELF file
+//   - the debug link in the .debug subdirectory of the directory of the ELF file
+//   - the debug link in /usr/lib/debug followed by the directory of the ELF file
+func FindDebugFile(name string) (string, error) {
+	f, err := elf.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if desc, err := readNoteDesc(f, gnuBuildIDSection); err == nil && len(desc) > 1 {
+		buildID := hex.EncodeToString(desc)
+		for _, dir := range DebugDirs {
+			path := filepath.Join(dir, buildIDDir, buildID[:2], buildID[2:]+debugFileExt)
+			if isFile(path) {
+				return path, nil
+			}
+		}
+	}
+
+	link, crc, err := readDebugLink(f)
+	if err != nil {
+		return "", ErrDebugFileNotFound
+	}
+
+	absName, err := filepath.Abs(name)
+	if err != nil {
+		return "", err
+	}
+	exeDir := filepath.Dir(absName)
+	candidates := []string{
+		filepath.Join(exeDir, link),
+		filepath.Join(exeDir, debugFileExt, link),
+	}
+	for _, dir := range DebugDirs {
+		candidates = append(candidates, filepath.Join(dir, exeDir, link))
+	}
+
+	for _, path := range candidates {
+		// The debug link may point to the ELF file itself.
+		if path == absName || !isFile(path) {
+			continue
+		}
+		if ok, err := checkCRC(path, crc); err != nil || !ok {
+			continue
+		}
+		return path, nil
+	}
+
+	return "", ErrDebugFileNotFound
+}
+
+// SymbolOffset returns the file offset of the symbol value, that is its
+// virtual address, in the executable segments of the ELF file.
+func SymbolOffset(f *elf.File, value uint64) (uint64, error) {
+	for _, prog := range f.Progs {
+		if prog.Type != elf.PT_LOAD || prog.Flags&elf.PF_X == 0 {
+			continue
+		}
+		if value >= prog.Vaddr && value < prog.Vaddr+prog.Memsz {
+			return value - prog.Vaddr + prog.Off, nil
+		}
+	}
+
+	return 0, ErrSymbolNotInExecSegments
+}
+
+// readDebugLink returns the file name and the CRC32 checksum of the debug
+// info file from the debug link section of the ELF file.
+func readDebugLink(f *elf.File) (string, uint32, error) {
+	s := f.Section(debugLinkSection)
+	if s == nil {
+		return "", 0, ErrDebugFileNotFound
+	}
+	data, err := s.Data()
+	if err != nil {
+		return "", 0, err
+	}
+
+	end := bytes.IndexByte(data, 0)
+	if end <= 0 {
+		return "", 0, ErrDebugFileNotFound
+	}
+	crcOff := align4(uint32(end + 1))
+	if uint64(crcOff)+4 > uint64(len(data)) {
+		return "", 0, ErrDebugFileNotFound
+	}
+
+	return string(data[:end]), f.ByteOrder.Uint32(data[crcOff : crcOff+4]), nil
+}
+
+func checkCRC(path string, crc uint32) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	return crc32.ChecksumIEEE(data) == crc, nil
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
@@ -0,0 +1,9 @@
+package static
+
+import "github.com/ianlancetaylor/demangle"
+
+// Demangle returns the demangled name of a C++ (Itanium ABI) or Rust symbol,
+// or the name itself when it is not mangled.
+func Demangle(name string) string {
+	return demangle.Filter(name)
+}
@@ -0,0 +1,22 @@
+package static_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxgio92/xcover/pkg/static"
+)
+
+func TestDemangle(t *testing.T) {
+	for name, expected := range map[string]string{
+		"_ZN3foo3Bar3runEv":                      "foo::Bar::run()",
+		"_Z3addii":                               "add(int, int)",
+		"_ZN4core3fmt5write17h0123456789abcdefE": "core::fmt::write",
+		"_RNvCs1234_7mycrate3foo":                "mycrate::foo",
+		"main.fooFunction":                       "main.fooFunction",
+		"malloc":                                 "malloc",
+	} {
+		require.Equal(t, expected, static.Demangle(name), name)
+	}
+}
@@ -0,0 +1,150 @@
+package static
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+)
+
+// SourceLocation is the location in the source code where a function is declared.
+type SourceLocation struct {
+	// Name is the name of the symbol of the function.
+	Name     string
+	File     string
+	Line     int
+	CompUnit string
+}
+
+// GetFuncSourceLocations returns the source locations of the functions
+// described by the DWARF debug info of the ELF file, indexed by their entry
+// address, that is the value of their symbol, as the names of the local
+// functions of different compilation units can be the same.
+func GetFuncSourceLocations(name string) (map[uint64]SourceLocation, error) {
+	f, err := elf.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	d, err := f.DWARF()
+	if err != nil {
+		return nil, err
+	}
+
+	return getFuncSourceLocations(d)
+}
+
+func getFuncSourceLocations(d *dwarf.Data) (map[uint64]SourceLocation, error) {
+	locations := make(map[uint64]SourceLocation)
+
+	// Declarations referenced by definitions, like C++ member functions
+	// declared in the class and defined out of it.
+	decls := make(map[dwarf.Offset]*dwarf.Entry)
+
+	var (
+		files    []*dwarf.LineFile
+		compUnit string
+	)
+	r := d.Reader()
+	for {
+		entry, err := r.Next()
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			break
+		}
+
+		switch entry.Tag {
+		case dwarf.TagCompileUnit:
+			compUnit, _ = entry.Val(dwarf.AttrName).(string)
+			files = nil
+			lr, err := d.LineReader(entry)
+			if err == nil && lr != nil {
+				files = lr.Files()
+			}
+			continue
+		case dwarf.TagSubprogram:
+		default:
+			continue
+		}
+		decls[entry.Offset] = entry
+
+		// Skip declarations and abstract instances of inlined functions.
+		if declaration, _ := entry.Val(dwarf.AttrDeclaration).(bool); declaration {
+			continue
+		}
+		if _, ok := entry.Val(dwarf.AttrInline).(int64); ok {
+			continue
+		}
+
+		spec := entry
+		for _, attr := range []dwarf.Attr{dwarf.AttrSpecification, dwarf.AttrAbstractOrigin} {
+			if off, ok := entry.Val(attr).(dwarf.Offset); ok {
+				if decl, ok := decls[off]; ok {
+					spec = decl
+				}
+			}
+		}
+
+		symName := entryName(entry, spec)
+		if symName == "" {
+			continue
+		}
+		addr, ok := entryAddress(d, entry)
+		if !ok {
+			continue
+		}
+
+		fileIdx, ok := entryVal(entry, spec, dwarf.AttrDeclFile).(int64)
+		if !ok || fileIdx < 0 || int(fileIdx) >= len(files) || files[fileIdx] == nil {
+			continue
+		}
+		line, _ := entryVal(entry, spec, dwarf.AttrDeclLine).(int64)
+
+		locations[addr] = SourceLocation{
+			Name:     symName,
+			File:     files[fileIdx].Name,
+			Line:     int(line),
+			CompUnit: compUnit,
+		}
+	}
+
+	return locations, nil
+}
+
+// entryName returns the name of the symbol of a subprogram, that is the
+// linkage name when present, like for mangled C++ and Rust names.
+func entryName(entry, spec *dwarf.Entry) string {
+	for _, attr := range []dwarf.Attr{dwarf.AttrLinkageName, dwarf.AttrName} {
+		if name, ok := entryVal(entry, spec, attr).(string); ok && name != "" {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// entryAddress returns the entry address of a subprogram, that is its low
+// PC, or the start of its first address range when not contiguous.
+func entryAddress(d *dwarf.Data, entry *dwarf.Entry) (uint64, bool) {
+	if lowPC, ok := entry.Val(dwarf.AttrLowpc).(uint64); ok {
+		return lowPC, true
+	}
+
+	ranges, err := d.Ranges(entry)
+	if err != nil || len(ranges) == 0 {
+		return 0, false
+	}
+
+	return ranges[0][0], true
+}
+
+// entryVal returns the value of the attribute of the entry, falling back
+// to the one of its specification.
+func entryVal(entry, spec *dwarf.Entry, attr dwarf.Attr) interface{} {
+	if v := entry.Val(attr); v != nil {
+		return v
+	}
+
+	return spec.Val(attr)
+}
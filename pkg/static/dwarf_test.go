@@ -0,0 +1,73 @@
+package static_test
+
+import (
+	"debug/elf"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxgio92/xcover/pkg/static"
+)
+
+var (
+	testBinary          = path.Join("..", "trace", "testdata", "gotest")
+	testDupStaticBinary = path.Join("..", "trace", "testdata", "dupstatic")
+)
+
+// symbolValues returns the values of the function symbols of the ELF file
+// with the name.
+func symbolValues(t *testing.T, file, name string) []uint64 {
+	f, err := elf.Open(file)
+	require.NoError(t, err)
+	defer f.Close()
+
+	syms, err := f.Symbols()
+	require.NoError(t, err)
+
+	var values []uint64
+	for _, sym := range syms {
+		if sym.Name == name && elf.ST_TYPE(sym.Info) == elf.STT_FUNC {
+			values = append(values, sym.Value)
+		}
+	}
+
+	return values
+}
+
+func TestGetFuncSourceLocations(t *testing.T) {
+	locations, err := static.GetFuncSourceLocations(testBinary)
+	require.NoError(t, err)
+	require.NotEmpty(t, locations)
+
+	values := symbolValues(t, testBinary, "main.fooFunction")
+	require.Len(t, values, 1)
+	loc, ok := locations[values[0]]
+	require.True(t, ok)
+	require.Equal(t, "main.fooFunction", loc.Name)
+	require.Equal(t, "test.go", path.Base(loc.File))
+	require.Equal(t, 9, loc.Line)
+	require.Equal(t, "main", loc.CompUnit)
+
+	_, err = static.GetFuncSourceLocations("nonexistent-binary-file")
+	require.Error(t, err)
+}
+
+func TestGetFuncSourceLocations_DuplicateNames(t *testing.T) {
+	locations, err := static.GetFuncSourceLocations(testDupStaticBinary)
+	require.NoError(t, err)
+
+	// The helper functions are local to different compilation units.
+	values := symbolValues(t, testDupStaticBinary, "helper")
+	require.Len(t, values, 2)
+
+	files := make([]string, 0, len(values))
+	for _, v := range values {
+		loc, ok := locations[v]
+		require.True(t, ok)
+		require.Equal(t, "helper", loc.Name)
+		require.Equal(t, path.Base(loc.File), path.Base(loc.CompUnit))
+		files = append(files, path.Base(loc.File))
+	}
+	require.ElementsMatch(t, []string{"dupstatic_a.c", "dupstatic_b.c"}, files)
+}
@@ -0,0 +1,82 @@
+package static
+
+import (
+	"debug/elf"
+	"debug/gosym"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	goPclntabSection = ".gopclntab"
+	textSection      = ".text"
+)
+
+var (
+	// goSections are the sections the Go toolchain adds to the ELF executables.
+	goSections = []string{".go.buildinfo", goPclntabSection, ".note.go.buildid"}
+
+	ErrGoPclntabNotFound = errors.New("go pclntab not found")
+	ErrTextNotFound      = errors.New("text section not found")
+)
+
+// IsGoExecutable returns whether the ELF file has been built by the Go toolchain.
+func IsGoExecutable(name string) bool {
+	f, err := elf.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	for _, section := range goSections {
+		if f.Section(section) != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetGoFuncSyms returns the function symbols recovered from the pclntab of a
+// Go ELF executable, which is kept even when the symbol table is stripped,
+// like with -ldflags="-s -w".
+// The returned symbols are global functions, as the pclntab lacks bindings.
+func GetGoFuncSyms(f *elf.File) ([]elf.Symbol, error) {
+	pclntab := f.Section(goPclntabSection)
+	if pclntab == nil {
+		return nil, ErrGoPclntabNotFound
+	}
+	textIndex := -1
+	for i, section := range f.Sections {
+		if section.Name == textSection {
+			textIndex = i
+			break
+		}
+	}
+	if textIndex < 0 {
+		return nil, ErrTextNotFound
+	}
+	text := f.Sections[textIndex]
+
+	data, err := pclntab.Data()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read pclntab")
+	}
+	table, err := gosym.NewTable(nil, gosym.NewLineTable(data, text.Addr))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse pclntab")
+	}
+
+	syms := make([]elf.Symbol, 0, len(table.Funcs))
+	for _, fn := range table.Funcs {
+		syms = append(syms, elf.Symbol{
+			Name:    fn.Name,
+			Info:    elf.ST_INFO(elf.STB_GLOBAL, elf.STT_FUNC),
+			Section: elf.SectionIndex(textIndex),
+			Value:   fn.Entry,
+			Size:    fn.End - fn.Entry,
+		})
+	}
+
+	return syms, nil
+}
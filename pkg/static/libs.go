@@ -0,0 +1,130 @@
+package static
+
+import (
+	"debug/elf"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	originToken      = "$ORIGIN"
+	originTokenBrace = "${ORIGIN}"
+	libraryPathEnv   = "LD_LIBRARY_PATH"
+)
+
+var (
+	// LibDirs are the default directories where the shared libraries are looked up.
+	LibDirs = []string{
+		"/lib64", "/usr/lib64",
+		"/lib/x86_64-linux-gnu", "/usr/lib/x86_64-linux-gnu",
+		"/lib/aarch64-linux-gnu", "/usr/lib/aarch64-linux-gnu",
+		"/lib", "/usr/lib",
+		"/usr/local/lib",
+	}
+
+	ErrLibNotFound = errors.New("shared library not found")
+)
+
+// GetNeededLibs returns the paths of the shared libraries the ELF file
+// directly depends on (DT_NEEDED), resolved like the dynamic linker does:
+//   - DT_RPATH, when DT_RUNPATH is not present
+//   - LD_LIBRARY_PATH
+//   - DT_RUNPATH
+//   - the default library directories
+//
+// The libraries that cannot be resolved are skipped.
+func GetNeededLibs(name string) ([]string, error) {
+	f, err := elf.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	needed, err := f.ImportedLibraries()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read needed libraries")
+	}
+
+	dirs, err := libSearchDirs(f, name)
+	if err != nil {
+		return nil, err
+	}
+
+	libs := make([]string, 0, len(needed))
+	for _, soname := range needed {
+		path, err := findLib(soname, dirs)
+		if err != nil {
+			continue
+		}
+		libs = append(libs, path)
+	}
+
+	return libs, nil
+}
+
+// libSearchDirs returns the directories to look up the shared libraries
+// needed by the ELF file, in the dynamic linker order.
+func libSearchDirs(f *elf.File, name string) ([]string, error) {
+	absName, err := filepath.Abs(name)
+	if err != nil {
+		return nil, err
+	}
+	origin := filepath.Dir(absName)
+
+	rpath, _ := f.DynString(elf.DT_RPATH)
+	runpath, _ := f.DynString(elf.DT_RUNPATH)
+
+	var dirs []string
+	if len(runpath) == 0 {
+		dirs = append(dirs, splitSearchPath(rpath, origin)...)
+	}
+	dirs = append(dirs, splitSearchPath([]string{os.Getenv(libraryPathEnv)}, origin)...)
+	dirs = append(dirs, splitSearchPath(runpath, origin)...)
+	dirs = append(dirs, LibDirs...)
+
+	return dirs, nil
+}
+
+func splitSearchPath(paths []string, origin string) []string {
+	var dirs []string
+	for _, path := range paths {
+		for _, dir := range strings.Split(path, ":") {
+			if dir == "" {
+				continue
+			}
+			dir = strings.ReplaceAll(dir, originTokenBrace, origin)
+			dir = strings.ReplaceAll(dir, originToken, origin)
+			dirs = append(dirs, dir)
+		}
+	}
+
+	return dirs
+}
+
+func findLib(soname string, dirs []string) (string, error) {
+	// Needed entries with a slash are paths, not looked up.
+	if strings.Contains(soname, "/") {
+		if isFile(soname) {
+			return soname, nil
+		}
+		return "", errors.Wrap(ErrLibNotFound, soname)
+	}
+
+	for _, dir := range dirs {
+		path := filepath.Join(dir, soname)
+		if !isFile(path) {
+			continue
+		}
+		// Resolve the symlinks, like the versioned sonames, to get the path
+		// of the object mapped by the dynamic linker.
+		if realPath, err := filepath.EvalSymlinks(path); err == nil {
+			return realPath, nil
+		}
+		return path, nil
+	}
+
+	return "", errors.Wrap(ErrLibNotFound, soname)
+}
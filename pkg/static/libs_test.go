@@ -0,0 +1,52 @@
+package static_test
+
+import (
+	"debug/elf"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/maxgio92/xcover/pkg/static"
+)
+
+// dynamicBinary returns the path of a dynamically linked system executable.
+func dynamicBinary(t *testing.T) string {
+	t.Helper()
+
+	path, err := exec.LookPath("ls")
+	if err != nil {
+		t.Skip("ls not found")
+	}
+	f, err := elf.Open(path)
+	if err != nil {
+		t.Skip("ls is not an ELF file")
+	}
+	defer f.Close()
+	if libs, err := f.ImportedLibraries(); err != nil || len(libs) == 0 {
+		t.Skip("ls is not dynamically linked")
+	}
+
+	return path
+}
+
+func TestGetNeededLibs(t *testing.T) {
+	libs, err := static.GetNeededLibs(dynamicBinary(t))
+	require.NoError(t, err)
+	require.NotEmpty(t, libs)
+
+	var libc bool
+	for _, lib := range libs {
+		require.FileExists(t, lib)
+		if filepath.Base(lib) == "libc.so.6" {
+			libc = true
+		}
+	}
+	require.True(t, libc)
+
+	// Statically linked executable.
+	libs, err = static.GetNeededLibs(testBinary)
+	require.NoError(t, err)
+	require.Empty(t, libs)
+}
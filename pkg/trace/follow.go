@@ -0,0 +1,87 @@
+package trace
+
+import (
+	"context"
+	"time"
+
+	"github.com/maxgio92/xcover/pkg/procfs"
+)
+
+// followLibs periodically looks up the shared libraries mapped by the tracee
+// processes, like the ones loaded with dlopen, and traces the functions of
+// the ones matching the pattern, until the context is done.
+func (t *UserTracer) followLibs(ctx context.Context) {
+	t.logger.Info().
+		Str("pattern", t.followLibsPattern).
+		Dur("interval", t.followLibsInterval).
+		Msg("following shared libraries")
+
+	// Paths already looked at by each tracee, to not retry the libraries
+	// failed to load.
+	seen := make([]map[string]struct{}, len(t.tracees))
+	for i, tracee := range t.tracees {
+		seen[i] = map[string]struct{}{resolvePath(tracee.exePath): {}}
+	}
+
+	ticker := time.NewTicker(t.followLibsInterval)
+	defer ticker.Stop()
+
+	for {
+		for i, tracee := range t.tracees {
+			for _, path := range t.scanLibs(tracee, seen[i]) {
+				t.attachObjectProbe(ctx, tracee, path)
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// scanLibs loads the functions of the new shared libraries mapped by the
+// tracee processes that match the pattern, and returns their paths.
+func (t *UserTracer) scanLibs(tracee *UserTracee, seen map[string]struct{}) []string {
+	var libs []string
+
+	pids := []int{t.pid}
+	if t.pid <= 0 {
+		var err error
+		pids, err = procfs.GetPids(tracee.exePath)
+		if err != nil {
+			t.logger.Debug().Err(err).Msg("failed to get tracee processes")
+			return nil
+		}
+	}
+
+	for _, pid := range pids {
+		paths, err := procfs.GetExecMappings(pid)
+		if err != nil {
+			// The process can exit meanwhile.
+			t.logger.Debug().Err(err).Int("pid", pid).Msg("failed to get process mappings")
+			continue
+		}
+
+		for _, path := range paths {
+			if _, ok := seen[path]; ok {
+				continue
+			}
+			seen[path] = struct{}{}
+
+			if !t.followLibsRegexp.MatchString(path) || tracee.hasObject(path) {
+				continue
+			}
+
+			if err := tracee.AddLib(path); err != nil {
+				t.logger.Warn().Err(err).Str("lib_path", path).Msg("failed to load library functions")
+				continue
+			}
+			t.logger.Info().Str("lib_path", path).Int("pid", pid).Msg("tracing shared library")
+			libs = append(libs, path)
+		}
+	}
+
+	return libs
+}
@@ -0,0 +1,123 @@
+package trace
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/maxgio92/xcover/internal/utils"
+	"github.com/maxgio92/xcover/pkg/coverage"
+	"github.com/maxgio92/xcover/pkg/healthcheck"
+)
+
+// Snapshot writes the current report in the format to the path, while
+// tracing goes on.
+func (t *UserTracer) Snapshot(format coverage.ReportFormat, path string) error {
+	if err := format.Validate(); err != nil {
+		return err
+	}
+
+	report, err := t.Coverage()
+	if err != nil {
+		return err
+	}
+
+	return t.writeReportFile(report, format, path)
+}
+
+// Reset resets the coverage, that is the functions acknowledged and their
+// hits, both in the probe and in the tracer, so that the functions called
+// from now on are acknowledged again. The functions attached are kept.
+// When the path is not empty, the report before the reset is written in
+// the format to it.
+func (t *UserTracer) Reset(format coverage.ReportFormat, path string) error {
+	t.resetMu.Lock()
+	defer t.resetMu.Unlock()
+
+	if path != "" {
+		if err := t.Snapshot(format, path); err != nil {
+			return errors.Wrap(err, "failed to write the report before the reset")
+		}
+	}
+
+	// The events reported from now on are tagged with the next generation,
+	// the ones still queued are discarded.
+	gen := t.generation + 1
+	if t.probe != nil {
+		if err := t.probe.SetGeneration(gen); err != nil {
+			return errors.Wrap(err, "failed to set the probe generation")
+		}
+	}
+	t.generation = gen
+
+	if t.probe != nil {
+		if err := t.probe.Reset(); err != nil {
+			return errors.Wrap(err, "failed to reset the probe")
+		}
+	}
+	t.ack.Clear()
+	t.ackByLabel.Clear()
+	t.logger.Info().Msg("coverage reset")
+
+	return nil
+}
+
+// Status returns the status of the tracer.
+func (t *UserTracer) Status() (*healthcheck.Status, error) {
+	funcsTraced := t.funcsLen()
+	funcsAck := utils.LenSyncMap(&t.ack)
+
+	var cov float64
+	if funcsTraced > 0 {
+		cov = float64(funcsAck) / float64(funcsTraced) * 100
+	}
+
+	exePaths := make([]string, 0, len(t.tracees))
+	for _, tracee := range t.tracees {
+		exePaths = append(exePaths, tracee.exePath)
+	}
+
+	status := &healthcheck.Status{
+		Pid:            os.Getpid(),
+		ExePath:        t.tracees[0].exePath,
+		ExePaths:       exePaths,
+		FuncsTraced:    funcsTraced,
+		FuncsAttached:  atomic.LoadUint64(&t.funcsAttached),
+		FuncsFailed:    atomic.LoadUint64(&t.funcsFailed),
+		FuncsAck:       funcsAck,
+		CovByFunc:      cov,
+		EventsConsumed: atomic.LoadUint64(&t.consumedTotal),
+		Uptime:         time.Since(t.startTime),
+	}
+
+	if t.probe != nil {
+		util, err := t.probe.GetEventBufUtilization()
+		if err != nil {
+			t.logger.Debug().Err(err).Msg("failed to get events buffer utilization")
+		}
+		status.EventBufUtil = util
+	}
+
+	return status, nil
+}
+
+// Coverage returns the current report, while tracing goes on.
+func (t *UserTracer) Coverage() (*coverage.CoverageReport, error) {
+	// Shared libraries can be added meanwhile.
+	for _, tracee := range t.tracees {
+		tracee.mu.RLock()
+		defer tracee.mu.RUnlock()
+	}
+
+	return t.buildReport(), nil
+}
+
+// Shutdown stops tracing, that writes the report.
+func (t *UserTracer) Shutdown() error {
+	t.logger.Info().Msg("shutdown requested")
+	t.cancel()
+
+	return nil
+}
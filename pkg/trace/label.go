@@ -0,0 +1,79 @@
+package trace
+
+import (
+	"sort"
+)
+
+// labelID identifies a label in the probe, 0 means none.
+type labelID uint32
+
+// labeledCookie is a function acknowledged while a label was marked.
+type labeledCookie struct {
+	label  labelID
+	cookie cookie
+}
+
+// Mark sets the label of the functions called from now on, like the name
+// of the test being run, empty meaning none. The functions are acknowledged
+// for each label they are called with.
+func (t *UserTracer) Mark(label string) error {
+	t.labelsMu.Lock()
+	defer t.labelsMu.Unlock()
+
+	var id labelID
+	if label != "" {
+		var ok bool
+		id, ok = t.labelIDs[label]
+		if !ok {
+			t.labels = append(t.labels, label)
+			id = labelID(len(t.labels))
+			t.labelIDs[label] = id
+		}
+	}
+
+	if err := t.probe.SetLabel(uint32(id)); err != nil {
+		return err
+	}
+	t.logger.Info().Str("label", label).Msg("marked label")
+
+	return nil
+}
+
+// labelName returns the name of the label.
+func (t *UserTracer) labelName(id labelID) (string, bool) {
+	t.labelsMu.Lock()
+	defer t.labelsMu.Unlock()
+
+	if id == 0 || int(id) > len(t.labels) {
+		return "", false
+	}
+
+	return t.labels[id-1], true
+}
+
+// getFuncsAckByLabel returns the functions of the tracee acknowledged for
+// each label. It returns nil when no label has been marked.
+func (t *UserTracer) getFuncsAckByLabel(tracee *UserTracee) map[string][]string {
+	var ackByLabel map[string][]string
+	t.ackByLabel.Range(func(k, _ interface{}) bool {
+		lc := k.(labeledCookie)
+		label, ok := t.labelName(lc.label)
+		if !ok {
+			return true
+		}
+		fun, ok := tracee.funcs[lc.cookie]
+		if !ok {
+			return true
+		}
+		if ackByLabel == nil {
+			ackByLabel = make(map[string][]string)
+		}
+		ackByLabel[label] = append(ackByLabel[label], fun.displayName())
+		return true
+	})
+	for _, names := range ackByLabel {
+		sort.Strings(names)
+	}
+
+	return ackByLabel
+}
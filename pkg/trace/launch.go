@@ -0,0 +1,49 @@
+package trace
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// programStopTimeout is the time to wait for the program to exit when
+// terminated, before killing it.
+const programStopTimeout = 5 * time.Second
+
+// startProgram starts the program of the first tracee, whose process tree,
+// that can run the programs of the other tracees, is traced since
+// its creation, hence before the program executes its first instruction.
+// The program is terminated when the context is done.
+func (t *UserTracer) startProgram(ctx context.Context) (*exec.Cmd, error) {
+	// The probe tracks the children of the traced processes when they are
+	// created, so trace the tracer until the program is started.
+	self := os.Getpid()
+	if err := t.probe.AddTracedPid(self); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := t.probe.RemoveTracedPid(self); err != nil {
+			t.logger.Warn().Err(err).Msg("failed to stop tracing the tracer process")
+		}
+	}()
+
+	cmd := exec.CommandContext(ctx, t.tracees[0].exePath, t.args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Cancel = func() error {
+		return cmd.Process.Signal(syscall.SIGTERM)
+	}
+	cmd.WaitDelay = programStopTimeout
+
+	if err := cmd.Start(); err != nil {
+		return nil, errors.Wrapf(err, "failed to start %s", t.tracees[0].exePath)
+	}
+	t.logger.Info().Int("pid", cmd.Process.Pid).Strs("args", t.args).Msg("started the program")
+
+	return cmd, nil
+}
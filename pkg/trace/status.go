@@ -18,7 +18,7 @@ func (t *UserTracer) printStatusBar(ctx context.Context, eventsCh, feedCh chan [
 		1*time.Second, // bar refresh interval.
 		func() {
 			output.PrintRight(output.PrettyTraceStatus(
-				float64(utils.LenSyncMap(&t.ack))/float64(len(t.tracee.funcs))*100,
+				float64(utils.LenSyncMap(&t.ack))/float64(t.funcsLen())*100,
 				atomic.SwapUint64(&t.consumed, 0), // events rate reset at each bar refresh.
 				len(eventsCh)/probe.EventsChBufSize*100,
 				len(feedCh)/feedChBufSize*100,
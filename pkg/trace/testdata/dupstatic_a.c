@@ -0,0 +1,14 @@
+/*
+ * Local functions with the same name in different compilation units.
+ * Build with: gcc -g -O0 -o dupstatic dupstatic_a.c dupstatic_b.c
+ */
+
+static int helper(int x)
+{
+	return x + 1;
+}
+
+int a(int x)
+{
+	return helper(x);
+}
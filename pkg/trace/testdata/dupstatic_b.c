@@ -0,0 +1,13 @@
+/* See dupstatic_a.c. */
+
+int a(int x);
+
+static int helper(int x)
+{
+	return x * 2;
+}
+
+int main(void)
+{
+	return helper(a(0)) - 2;
+}
@@ -7,10 +7,18 @@ import (
 
 type UserTraceeOptions struct {
 	exePath           string
+	debugFilePath     string
+	libPaths          []string
+	neededLibs        bool
 	symPatternInclude string
 	symPatternExclude string
 	symBindInclude    []elf.SymBind
 	symBindExclude    []elf.SymBind
+	// demangle is whether to demangle the C++ and Rust function names.
+	demangle bool
+	// symMatchDemangled is whether the include and exclude patterns
+	// match the demangled function names instead of the raw ones.
+	symMatchDemangled bool
 
 	logger log.Logger
 }
@@ -23,6 +31,24 @@ func WithTraceeExePath(path string) UserTraceeOption {
 	}
 }
 
+func WithTraceeDebugFilePath(path string) UserTraceeOption {
+	return func(o *UserTracee) {
+		o.debugFilePath = path
+	}
+}
+
+func WithTraceeLibPaths(paths ...string) UserTraceeOption {
+	return func(o *UserTracee) {
+		o.libPaths = paths
+	}
+}
+
+func WithTraceeNeededLibs(neededLibs bool) UserTraceeOption {
+	return func(o *UserTracee) {
+		o.neededLibs = neededLibs
+	}
+}
+
 func WithTraceeSymPatternInclude(patternInclude string) UserTraceeOption {
 	return func(o *UserTracee) {
 		o.symPatternInclude = patternInclude
@@ -47,6 +73,18 @@ func WithTraceeSymBindExclude(symBind ...elf.SymBind) UserTraceeOption {
 	}
 }
 
+func WithTraceeDemangle(demangle bool) UserTraceeOption {
+	return func(o *UserTracee) {
+		o.demangle = demangle
+	}
+}
+
+func WithTraceeSymMatchDemangled(matchDemangled bool) UserTraceeOption {
+	return func(o *UserTracee) {
+		o.symMatchDemangled = matchDemangled
+	}
+}
+
 func WithTraceeLogger(logger log.Logger) UserTraceeOption {
 	return func(o *UserTracee) {
 		o.logger = logger
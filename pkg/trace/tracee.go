@@ -2,29 +2,71 @@ package trace
 
 import (
 	"debug/elf"
+	"fmt"
+	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
+	"sync"
 
-	"github.com/aquasecurity/libbpfgo/helpers"
-	"github.com/maxgio92/xcover/internal/utils"
+	"github.com/maxgio92/xcover/pkg/static"
 	"github.com/pkg/errors"
 )
 
 type UserTracee struct {
-	file  *elf.File
-	funcs map[cookie]funcInfo
+	// ELF objects being traced, that are the executable,
+	// always the first, and the shared libraries.
+	objects []*elfObject
+	// Functions by their probe cookie, that is unique to the function
+	// address in an ELF object.
+	funcs      map[cookie]funcInfo
+	lastCookie cookie
+	// mu guards the objects and the functions, that can be added
+	// while tracing.
+	mu sync.RWMutex
 	*UserTraceeOptions
 }
 
+// elfObject is an ELF object whose functions are traced.
+type elfObject struct {
+	path string
+	file *elf.File
+	// ELF file the symbols are read from, that is either the object
+	// or its separate debug info file.
+	symFile     *elf.File
+	symFilePath string
+	// lib is whether the object is a shared library.
+	lib bool
+}
+
 type cookie uint64
 
+// traceeCookieShift is the bit shift of the tracee index in the cookies.
+const traceeCookieShift = 32
+
 type funcInfo struct {
-	name   string
-	offset uint64
+	// Function name, demangled when enabled.
+	name string
+	// Raw function symbol name, that is mangled for C++ and Rust.
+	rawName string
+	bind    elf.SymBind
+	// Names of the other symbols at the same address.
+	aliases []string
+	// Unique name in the reports, set when other functions have the same
+	// name, like static functions of different compilation units.
+	reportName string
+	offset     uint64
+	// Path of the ELF object the function belongs to.
+	object string
+	// Source location, resolved from the DWARF debug info when present.
+	file     string
+	line     int
+	compUnit string
 }
 
 func NewUserTracee(opts ...UserTraceeOption) *UserTracee {
 	tracee := &UserTracee{
-		UserTraceeOptions: &UserTraceeOptions{},
+		UserTraceeOptions: &UserTraceeOptions{demangle: true},
 		funcs:             make(map[cookie]funcInfo, 0),
 	}
 	for _, opt := range opts {
@@ -42,32 +84,134 @@ func (t *UserTracee) Init() error {
 
 	t.logger.Info().
 		Str("exe_path", t.exePath).
+		Strs("lib_paths", t.libPaths).
+		Bool("needed_libs", t.neededLibs).
 		Str("include", t.symPatternInclude).
 		Str("exclude", t.symPatternExclude).
 		Msg("collecting functions")
 
-	t.file, err = elf.Open(t.exePath)
+	exe, err := t.openObject(t.exePath, t.debugFilePath, false)
 	if err != nil {
-		return errors.Wrap(err, "filed to open elf file")
-	}
-	if t.file == nil {
-		return ErrElfFileNil
+		return err
 	}
+	t.objects = []*elfObject{exe}
 
-	if err = t.loadFunctions(); err != nil {
+	if err = t.loadFunctions(exe); err != nil {
 		// Fail fast when the tracee binary is stripped.
 		if errors.Is(err, elf.ErrNoSymbols) || errors.Is(err, ErrNoFunctionSymbols) {
 			return err
 		}
 		t.logger.Warn().Err(err).Msg("failed to load functions")
 	}
+
+	libPaths, err := t.getLibPaths()
+	if err != nil {
+		return err
+	}
+	for _, path := range libPaths {
+		if err := t.AddLib(path); err != nil {
+			t.logger.Warn().Err(err).Str("lib_path", path).Msg("failed to load library functions")
+		}
+	}
+
 	t.logger.Info().
 		Int("count", len(t.funcs)).
+		Int("objects", len(t.objects)).
 		Msg("functions collected")
 
 	return nil
 }
 
+// AddLib loads the functions of the shared library to trace them along with
+// the executable ones.
+func (t *UserTracee) AddLib(path string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// The same library can be specified through symbolic links, while the
+	// process mappings have the resolved paths.
+	path = resolvePath(path)
+
+	for _, obj := range t.objects {
+		if obj.path == path {
+			return nil
+		}
+	}
+
+	lib, err := t.openObject(path, "", true)
+	if err != nil {
+		return err
+	}
+	if err := t.loadFunctions(lib); err != nil {
+		return err
+	}
+	t.objects = append(t.objects, lib)
+
+	return nil
+}
+
+// getLibPaths returns the paths of the shared libraries to trace, that are
+// the ones specified and, when enabled, the ones needed by the executable.
+func (t *UserTracee) getLibPaths() ([]string, error) {
+	paths := append([]string{}, t.libPaths...)
+	if t.neededLibs {
+		needed, err := static.GetNeededLibs(t.exePath)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get needed libraries")
+		}
+		paths = append(paths, needed...)
+	}
+
+	return paths, nil
+}
+
+// openObject opens the ELF object and the file to read the symbols from.
+func (t *UserTracee) openObject(path, debugFilePath string, lib bool) (*elfObject, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "filed to open elf file")
+	}
+	if f == nil {
+		return nil, ErrElfFileNil
+	}
+
+	obj := &elfObject{path: path, file: f, lib: lib}
+	if err = t.openSymFile(obj, debugFilePath); err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+// openSymFile opens the ELF file to read the object symbols from, that is the
+// debug file when specified, the object when not stripped, otherwise the
+// separate debug info file looked up by build ID or debug link, if any.
+func (t *UserTracee) openSymFile(obj *elfObject, debugFilePath string) error {
+	obj.symFile, obj.symFilePath = obj.file, obj.path
+
+	path := debugFilePath
+	if path == "" {
+		if static.HasSymbols(obj.file) {
+			return nil
+		}
+		var err error
+		path, err = static.FindDebugFile(obj.path)
+		if err != nil {
+			t.logger.Debug().Err(err).Str("path", obj.path).Msg("separate debug file not found")
+			return nil
+		}
+	}
+
+	t.logger.Info().Str("debug_file", path).Msg("reading symbols from debug file")
+	f, err := elf.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to open debug file")
+	}
+	obj.symFile, obj.symFilePath = f, path
+
+	return nil
+}
+
 func (t *UserTracee) validate() error {
 	if t.exePath == "" {
 		return ErrExePathEmpty
@@ -76,8 +220,8 @@ func (t *UserTracee) validate() error {
 	return nil
 }
 
-func (t *UserTracee) loadFunctions() error {
-	funcSyms, err := t.getFuncSyms()
+func (t *UserTracee) loadFunctions(obj *elfObject) error {
+	funcSyms, err := t.getFuncSyms(obj)
 	if err != nil {
 		return err
 	}
@@ -85,35 +229,218 @@ func (t *UserTracee) loadFunctions() error {
 		return ErrNoFunctionSymbols
 	}
 
+	locations, err := static.GetFuncSourceLocations(obj.symFilePath)
+	if err != nil {
+		t.logger.Debug().Err(err).Str("path", obj.symFilePath).Msg("failed to get function source locations")
+	}
+
 	t.logger.Debug().
 		Int("functions", len(funcSyms)).
-		Str("exe_path", t.exePath).
+		Str("path", obj.path).
 		Str("include", t.symPatternInclude).
 		Str("exclude", t.symPatternExclude).
 		Msg("getting function offsets from symbols")
+
+	// Symbols at the same offset are aliases of the same function,
+	// hence traced with a single probe.
+	byOffset := make(map[uint64]cookie)
 	for _, sym := range funcSyms {
-		offset, err := helpers.SymbolToOffset(t.exePath, sym.Name)
+		// Offsets are relative to the object, as the debug file
+		// does not contain the code.
+		offset, err := static.SymbolOffset(obj.file, sym.Value)
 		if err != nil {
-			t.logger.Debug().Err(err).Str("symbol", sym.Name).Str("exe_path", t.exePath).Msg("failed to get function offset")
+			t.logger.Debug().Err(err).Str("symbol", sym.Name).Str("path", obj.path).Msg("failed to get function offset")
+			continue
 		}
-		t.funcs[cookie(utils.Hash(sym.Name))] = funcInfo{
-			name:   sym.Name,
-			offset: uint64(offset),
+		loc := locations[sym.Value]
+
+		if c, ok := byOffset[offset]; ok {
+			t.funcs[c] = t.funcs[c].withAlias(t.funcName(sym.Name), sym, loc)
+			continue
+		}
+
+		c := t.nextCookie()
+		byOffset[offset] = c
+		t.funcs[c] = funcInfo{
+			name:     t.funcName(sym.Name),
+			rawName:  sym.Name,
+			bind:     elf.ST_BIND(sym.Info),
+			offset:   offset,
+			object:   obj.path,
+			file:     loc.File,
+			line:     loc.Line,
+			compUnit: loc.CompUnit,
 		}
 	}
-	if len(t.funcs) == 0 {
+	if len(byOffset) == 0 {
 		return ErrNoOffsets
 	}
 
+	cookies := make([]cookie, 0, len(byOffset))
+	for _, c := range byOffset {
+		cookies = append(cookies, c)
+	}
+	t.disambiguateFuncs(cookies)
+
 	return nil
 }
 
-func (t *UserTracee) getFuncSyms() ([]elf.Symbol, error) {
+// setCookieNamespace makes the cookies of the functions start from the
+// namespace of the tracee index, for the cookies of the tracees of a tracer
+// to be unique.
+func (t *UserTracee) setCookieNamespace(index int) {
+	t.lastCookie = cookie(index) << traceeCookieShift
+}
+
+// nextCookie returns a new cookie, not used by other functions.
+func (t *UserTracee) nextCookie() cookie {
+	for {
+		t.lastCookie++
+		if _, ok := t.funcs[t.lastCookie]; !ok {
+			return t.lastCookie
+		}
+	}
+}
+
+// withAlias returns the function with the alias symbol, that becomes the
+// primary name when it has a stronger binding, like a global symbol over a
+// local or weak one.
+func (f funcInfo) withAlias(name string, sym elf.Symbol, loc static.SourceLocation) funcInfo {
+	bind := elf.ST_BIND(sym.Info)
+	if bindRank(bind) < bindRank(f.bind) {
+		f.aliases = append(f.aliases, f.name)
+		f.name, f.rawName, f.bind = name, sym.Name, bind
+	} else {
+		f.aliases = append(f.aliases, name)
+	}
+	if f.file == "" {
+		f.file, f.line, f.compUnit = loc.File, loc.Line, loc.CompUnit
+	}
+
+	return f
+}
+
+func bindRank(bind elf.SymBind) int {
+	switch bind {
+	case elf.STB_GLOBAL:
+		return 0
+	case elf.STB_WEAK:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// disambiguateFuncs sets the report names of the functions of the cookies
+// with the same name, like static functions of different compilation units,
+// or with the name of another function, to their name followed by their
+// distinguishing source location or address.
+// The report names of the other functions are kept, as they can already be
+// reported, like when the functions of a shared library are added at runtime.
+func (t *UserTracee) disambiguateFuncs(cookies []cookie) {
+	byName := make(map[string][]cookie)
+	for _, c := range cookies {
+		name := t.funcs[c].name
+		byName[name] = append(byName[name], c)
+	}
+
+	// Names and report names of the other functions.
+	names := make(map[string]struct{}, len(t.funcs))
+	taken := make(map[string]struct{}, len(t.funcs))
+	for c, fn := range t.funcs {
+		if slices.Contains(byName[fn.name], c) {
+			continue
+		}
+		names[fn.name] = struct{}{}
+		taken[fn.displayName()] = struct{}{}
+	}
+
+	for name, group := range byName {
+		if _, ok := names[name]; !ok && len(group) < 2 {
+			continue
+		}
+		for _, disambiguate := range []func(funcInfo) string{
+			funcInfo.sourceSuffix,
+			funcInfo.addressSuffix,
+			funcInfo.objectAddressSuffix,
+		} {
+			reportNames := make(map[cookie]string, len(group))
+			unique := make(map[string]struct{}, len(group))
+			for _, c := range group {
+				suffix := disambiguate(t.funcs[c])
+				if suffix == "" {
+					break
+				}
+				reportName := fmt.Sprintf("%s (%s)", name, suffix)
+				if _, ok := taken[reportName]; ok {
+					break
+				}
+				reportNames[c] = reportName
+				unique[reportName] = struct{}{}
+			}
+			if len(unique) < len(group) {
+				continue
+			}
+			for c, reportName := range reportNames {
+				fn := t.funcs[c]
+				fn.reportName = reportName
+				t.funcs[c] = fn
+			}
+			break
+		}
+	}
+}
+
+func (f funcInfo) sourceSuffix() string {
+	if f.file == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", f.file, f.line)
+}
+
+func (f funcInfo) addressSuffix() string {
+	return fmt.Sprintf("%#x", f.offset)
+}
+
+func (f funcInfo) objectAddressSuffix() string {
+	return fmt.Sprintf("%s:%#x", filepath.Base(f.object), f.offset)
+}
+
+// displayName returns the unique name of the function in the reports.
+func (f funcInfo) displayName() string {
+	if f.reportName != "" {
+		return f.reportName
+	}
+	return f.name
+}
+
+// funcName returns the name of the function symbol, demangled when enabled.
+func (t *UserTracee) funcName(symName string) string {
+	if !t.demangle {
+		return symName
+	}
+	return static.Demangle(symName)
+}
+
+func (t *UserTracee) getFuncSyms(obj *elfObject) ([]elf.Symbol, error) {
 	var funcSyms []elf.Symbol
-	if t.file == nil {
+	if obj.symFile == nil {
 		return nil, ErrElfFileNil
 	}
-	syms, err := t.file.Symbols()
+	syms, err := obj.symFile.Symbols()
+	if errors.Is(err, elf.ErrNoSymbols) {
+		switch {
+		case static.IsGoExecutable(obj.path):
+			// Go executables stripped of the symbol table still have the pclntab.
+			t.logger.Info().Str("path", obj.path).Msg("reading symbols from go pclntab")
+			syms, err = static.GetGoFuncSyms(obj.file)
+		case obj.lib:
+			// Shared libraries stripped of the symbol table still export
+			// their functions in the dynamic symbol table.
+			t.logger.Info().Str("path", obj.path).Msg("reading symbols from dynamic symbol table")
+			syms, err = obj.file.DynamicSymbols()
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -123,6 +450,10 @@ func (t *UserTracee) getFuncSyms() ([]elf.Symbol, error) {
 		if elf.ST_TYPE(sym.Info) != elf.STT_FUNC {
 			continue
 		}
+		// Exclude undefined symbols, like the dynamic symbols imported.
+		if sym.Section == elf.SHN_UNDEF {
+			continue
+		}
 
 		if !t.ShouldIncludeSymbol(sym) {
 			continue
@@ -152,15 +483,19 @@ func (t *UserTracee) ShouldIncludeSymbol(sym elf.Symbol) bool {
 		}
 		return false
 	}
+	name := sym.Name
+	if t.symMatchDemangled {
+		name = static.Demangle(name)
+	}
 	// Exclude symbols that match a specific regex pattern.
 	if t.symPatternExclude != "" {
-		if regexp.MustCompile(t.symPatternExclude).MatchString(sym.Name) {
+		if regexp.MustCompile(t.symPatternExclude).MatchString(name) {
 			return false
 		}
 	}
 	// Include only symbols that match a specific regex pattern.
 	if t.symPatternInclude != "" {
-		if regexp.MustCompile(t.symPatternInclude).MatchString(sym.Name) {
+		if regexp.MustCompile(t.symPatternInclude).MatchString(name) {
 			return true
 		}
 		return false
@@ -170,28 +505,103 @@ func (t *UserTracee) ShouldIncludeSymbol(sym elf.Symbol) bool {
 }
 
 func (t *UserTracee) GetFuncOffsets() []uint64 {
-	offsets := make([]uint64, len(t.funcs))
-	for i := range t.funcs {
-		offsets = append(offsets, t.funcs[i].offset)
+	offsets := make([]uint64, 0, len(t.funcs))
+	for _, c := range t.sortedCookies() {
+		offsets = append(offsets, t.funcs[c].offset)
 	}
 
 	return offsets
 }
 
 func (t *UserTracee) GetFuncCookies() []uint64 {
-	cookies := make([]uint64, len(t.funcs))
-	for cookie := range t.funcs {
-		cookies = append(cookies, uint64(cookie))
+	cookies := make([]uint64, 0, len(t.funcs))
+	for _, c := range t.sortedCookies() {
+		cookies = append(cookies, uint64(c))
 	}
 
 	return cookies
 }
 
 func (t *UserTracee) GetFuncNames() []string {
-	names := make([]string, len(t.funcs))
-	for i := range t.funcs {
-		names = append(names, t.funcs[i].name)
+	names := make([]string, 0, len(t.funcs))
+	for _, c := range t.sortedCookies() {
+		names = append(names, t.funcs[c].displayName())
 	}
 
 	return names
 }
+
+// sortedCookies returns the cookies of the functions in order, for the
+// function getters to return the functions in the same order.
+func (t *UserTracee) sortedCookies() []cookie {
+	cookies := make([]cookie, 0, len(t.funcs))
+	for c := range t.funcs {
+		cookies = append(cookies, c)
+	}
+	sort.Slice(cookies, func(i, j int) bool { return cookies[i] < cookies[j] })
+
+	return cookies
+}
+
+// getObjectFuncs returns the offsets and the cookies of the functions of the
+// ELF object, in the same order.
+func (t *UserTracee) getObjectFuncs(path string) ([]uint64, []uint64) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var offsets, cookies []uint64
+	for c, fn := range t.funcs {
+		if fn.object != path {
+			continue
+		}
+		offsets = append(offsets, fn.offset)
+		cookies = append(cookies, uint64(c))
+	}
+
+	return offsets, cookies
+}
+
+// hasObject returns whether the functions of the ELF object are traced.
+func (t *UserTracee) hasObject(path string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	path = resolvePath(path)
+
+	for _, obj := range t.objects {
+		if obj.path == path {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolvePath returns the absolute path with the symbolic links resolved,
+// or the path when it cannot be resolved.
+func resolvePath(path string) string {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return path
+	}
+	if resolved, err = filepath.Abs(resolved); err != nil {
+		return path
+	}
+
+	return resolved
+}
+
+func (t *UserTracee) getFunc(c cookie) (funcInfo, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	fn, ok := t.funcs[c]
+	return fn, ok
+}
+
+func (t *UserTracee) funcsLen() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	return len(t.funcs)
+}
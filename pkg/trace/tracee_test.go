@@ -4,11 +4,13 @@ import (
 	"debug/elf"
 	"github.com/rs/zerolog"
 	"os"
+	"os/exec"
 	"path"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/maxgio92/xcover/pkg/static"
 	"github.com/maxgio92/xcover/pkg/trace"
 )
 
@@ -93,3 +95,226 @@ func TestUserTracee_IncludeExclude(t *testing.T) {
 	require.Error(t, err)
 	require.ErrorIs(t, err, trace.ErrNoFunctionSymbols)
 }
+
+// stripTestBinary writes to dir a copy of the test binary stripped of the
+// symbols, linked to a separate debug info file, and returns their paths.
+func stripTestBinary(t *testing.T, dir string) (string, string) {
+	t.Helper()
+
+	objcopy, err := exec.LookPath("objcopy")
+	if err != nil {
+		t.Skip("objcopy not found")
+	}
+
+	stripped := path.Join(dir, "gotest")
+	debugFile := path.Join(dir, "gotest.debug")
+	for _, args := range [][]string{
+		{"--only-keep-debug", testBinary, debugFile},
+		{"--strip-all", testBinary, stripped},
+		{"--add-gnu-debuglink=" + debugFile, stripped},
+	} {
+		out, err := exec.Command(objcopy, args...).CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+
+	return stripped, debugFile
+}
+
+func TestUserTracee_Init_DebugFile(t *testing.T) {
+	tracee := trace.NewUserTracee(
+		trace.WithTraceeExePath(testBinary),
+		trace.WithTraceeSymPatternInclude("^main\\."),
+	)
+	require.NoError(t, tracee.Init())
+	expected := tracee.GetFuncOffsets()
+
+	stripped, debugFile := stripTestBinary(t, t.TempDir())
+
+	// Nonexistent debug file.
+	tracee = trace.NewUserTracee(
+		trace.WithTraceeExePath(stripped),
+		trace.WithTraceeDebugFilePath(path.Join(t.TempDir(), "nonexistent.debug")),
+	)
+	require.ErrorIs(t, tracee.Init(), os.ErrNotExist)
+
+	// Debug file looked up by debug link.
+	tracee = trace.NewUserTracee(
+		trace.WithTraceeExePath(stripped),
+		trace.WithTraceeSymPatternInclude("^main\\."),
+	)
+	require.NoError(t, tracee.Init())
+	require.ElementsMatch(t, expected, tracee.GetFuncOffsets())
+
+	// Explicit debug file.
+	tracee = trace.NewUserTracee(
+		trace.WithTraceeExePath(stripped),
+		trace.WithTraceeDebugFilePath(debugFile),
+		trace.WithTraceeSymPatternInclude("^main\\."),
+	)
+	require.NoError(t, tracee.Init())
+	require.ElementsMatch(t, expected, tracee.GetFuncOffsets())
+}
+
+func TestUserTracee_Init_GoPclntab(t *testing.T) {
+	tracee := trace.NewUserTracee(
+		trace.WithTraceeExePath(testBinary),
+		trace.WithTraceeSymPatternInclude("^main\\."),
+	)
+	require.NoError(t, tracee.Init())
+	expectedOffsets := tracee.GetFuncOffsets()
+	expectedNames := tracee.GetFuncNames()
+
+	objcopy, err := exec.LookPath("objcopy")
+	if err != nil {
+		t.Skip("objcopy not found")
+	}
+	stripped := path.Join(t.TempDir(), "gotest")
+	out, err := exec.Command(objcopy, "--strip-all", testBinary, stripped).CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	// Symbols recovered from the pclntab of the stripped executable.
+	tracee = trace.NewUserTracee(
+		trace.WithTraceeExePath(stripped),
+		trace.WithTraceeSymPatternInclude("^main\\."),
+	)
+	require.NoError(t, tracee.Init())
+	require.ElementsMatch(t, expectedOffsets, tracee.GetFuncOffsets())
+	require.ElementsMatch(t, expectedNames, tracee.GetFuncNames())
+}
+
+func TestUserTracee_Init_Libs(t *testing.T) {
+	ls, err := exec.LookPath("ls")
+	if err != nil {
+		t.Skip("ls not found")
+	}
+	libs, err := static.GetNeededLibs(ls)
+	if err != nil || len(libs) == 0 {
+		t.Skip("no shared libraries found")
+	}
+	var libc string
+	for _, lib := range libs {
+		if path.Base(lib) == "libc.so.6" {
+			libc = lib
+		}
+	}
+	if libc == "" {
+		t.Skip("libc not found")
+	}
+
+	tracee := trace.NewUserTracee(
+		trace.WithTraceeExePath(testBinary),
+		trace.WithTraceeLibPaths(libc),
+		trace.WithTraceeSymPatternInclude("^main\\.fooFunction$|^malloc$"),
+	)
+	require.NoError(t, tracee.Init())
+	require.ElementsMatch(t, []string{"main.fooFunction", "malloc"}, tracee.GetFuncNames())
+}
+
+func TestUserTracee_IncludeExclude_Demangled(t *testing.T) {
+	sym := elf.Symbol{
+		Name: "_ZN3foo3Bar3runEv",
+		Info: elf.ST_INFO(elf.STB_GLOBAL, elf.STT_FUNC),
+	}
+
+	tracee := trace.NewUserTracee(
+		trace.WithTraceeSymPatternInclude("^foo::Bar::"),
+	)
+	require.False(t, tracee.ShouldIncludeSymbol(sym))
+
+	tracee = trace.NewUserTracee(
+		trace.WithTraceeSymPatternInclude("^foo::Bar::"),
+		trace.WithTraceeSymMatchDemangled(true),
+	)
+	require.True(t, tracee.ShouldIncludeSymbol(sym))
+
+	tracee = trace.NewUserTracee(
+		trace.WithTraceeSymPatternExclude("::run\\(\\)$"),
+		trace.WithTraceeSymMatchDemangled(true),
+	)
+	require.False(t, tracee.ShouldIncludeSymbol(sym))
+}
+
+func TestUserTracee_Init_Demangle(t *testing.T) {
+	var libstdcxx string
+	for _, dir := range static.LibDirs {
+		if _, err := os.Stat(path.Join(dir, "libstdc++.so.6")); err == nil {
+			libstdcxx = path.Join(dir, "libstdc++.so.6")
+			break
+		}
+	}
+	if libstdcxx == "" {
+		t.Skip("libstdc++ not found")
+	}
+
+	tracee := trace.NewUserTracee(
+		trace.WithTraceeExePath(testBinary),
+		trace.WithTraceeLibPaths(libstdcxx),
+		trace.WithTraceeSymPatternInclude("^main\\.fooFunction$|^_ZSt9terminatev$"),
+	)
+	require.NoError(t, tracee.Init())
+	require.ElementsMatch(t, []string{"main.fooFunction", "std::terminate()"}, tracee.GetFuncNames())
+
+	tracee = trace.NewUserTracee(
+		trace.WithTraceeExePath(testBinary),
+		trace.WithTraceeLibPaths(libstdcxx),
+		trace.WithTraceeSymPatternInclude("^main\\.fooFunction$|^std::terminate\\(\\)$"),
+		trace.WithTraceeSymMatchDemangled(true),
+	)
+	require.NoError(t, tracee.Init())
+	require.ElementsMatch(t, []string{"main.fooFunction", "std::terminate()"}, tracee.GetFuncNames())
+
+	tracee = trace.NewUserTracee(
+		trace.WithTraceeExePath(testBinary),
+		trace.WithTraceeLibPaths(libstdcxx),
+		trace.WithTraceeSymPatternInclude("^main\\.fooFunction$|^_ZSt9terminatev$"),
+		trace.WithTraceeDemangle(false),
+	)
+	require.NoError(t, tracee.Init())
+	require.ElementsMatch(t, []string{"main.fooFunction", "_ZSt9terminatev"}, tracee.GetFuncNames())
+}
+
+func TestUserTracee_Init_Aliases(t *testing.T) {
+	ls, err := exec.LookPath("ls")
+	if err != nil {
+		t.Skip("ls not found")
+	}
+	libs, err := static.GetNeededLibs(ls)
+	if err != nil {
+		t.Skip("no shared libraries found")
+	}
+	var libc string
+	for _, lib := range libs {
+		if path.Base(lib) == "libc.so.6" {
+			libc = lib
+		}
+	}
+	if libc == "" {
+		t.Skip("libc not found")
+	}
+
+	// malloc and __libc_malloc are the same function.
+	tracee := trace.NewUserTracee(
+		trace.WithTraceeExePath(testBinary),
+		trace.WithTraceeLibPaths(libc),
+		trace.WithTraceeSymPatternInclude("^main\\.fooFunction$|^malloc$|^__libc_malloc$"),
+	)
+	require.NoError(t, tracee.Init())
+	require.ElementsMatch(t, []string{"main.fooFunction", "malloc"}, tracee.GetFuncNames())
+	require.Len(t, tracee.GetFuncOffsets(), 2)
+	require.Len(t, tracee.GetFuncCookies(), 2)
+}
+
+func TestUserTracee_Init_DuplicateLocalFuncs(t *testing.T) {
+	// The helper functions are local to different compilation units.
+	tracee := trace.NewUserTracee(
+		trace.WithTraceeExePath(path.Join(testData, "dupstatic")),
+		trace.WithTraceeSymPatternInclude("^helper$"),
+	)
+	require.NoError(t, tracee.Init())
+
+	names := tracee.GetFuncNames()
+	require.Len(t, names, 2)
+	require.ElementsMatch(t, []string{"dupstatic_a.c:6)", "dupstatic_b.c:5)"}, []string{
+		path.Base(names[0]), path.Base(names[1]),
+	})
+}
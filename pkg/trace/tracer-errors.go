@@ -11,4 +11,6 @@ var (
 	ErrTraceeNil             = errors.New("trace is nil")
 	ErrTraceeExePathEmpty    = errors.New("tracee exe path is empty")
 	ErrTraceeFuncListEmpty   = errors.New("tracee function list is empty")
+	ErrPidWithLaunch         = errors.New("pid filter cannot be set when launching the tracee")
+	ErrPidWithTracees        = errors.New("pid filter cannot be set when tracing more tracees")
 )
@@ -2,17 +2,44 @@ package trace
 
 import (
 	"io"
+	"time"
 
 	log "github.com/rs/zerolog"
+
+	"github.com/maxgio92/xcover/pkg/coverage"
 )
 
 type UserTracerOptions struct {
 	cookiesMapName string
 
-	report  bool
-	status  bool
-	verbose bool
-	writer  io.Writer
+	pid int
+
+	// launch makes the tracer start the tracee program with args, and trace
+	// only its process tree.
+	launch bool
+	args   []string
+
+	// cgroup is the ID or path of the cgroup v2 whose hierarchy is traced only.
+	cgroup string
+
+	// followLibsPattern is the regex pattern of the paths of the shared
+	// libraries to trace when mapped at runtime by the tracee processes.
+	followLibsPattern  string
+	followLibsInterval time.Duration
+
+	// socketPath is the path of the socket file of the health check server.
+	socketPath string
+
+	hits          bool
+	report        bool
+	reportFormats []coverage.ReportFormat
+	// reportPath is the path of the report, whose extension is replaced
+	// for each format.
+	reportPath string
+	thresholds []coverage.Threshold
+	status     bool
+	verbose    bool
+	writer     io.Writer
 
 	logger log.Logger
 }
@@ -31,6 +58,36 @@ func WithTracerReport(report bool) UserTracerOpt {
 	}
 }
 
+func WithTracerReportFormats(formats ...coverage.ReportFormat) UserTracerOpt {
+	return func(opts *UserTracer) {
+		opts.reportFormats = formats
+	}
+}
+
+func WithTracerReportPath(path string) UserTracerOpt {
+	return func(opts *UserTracer) {
+		opts.reportPath = path
+	}
+}
+
+func WithTracerSocketPath(path string) UserTracerOpt {
+	return func(opts *UserTracer) {
+		opts.socketPath = path
+	}
+}
+
+func WithTracerThresholds(thresholds ...coverage.Threshold) UserTracerOpt {
+	return func(opts *UserTracer) {
+		opts.thresholds = thresholds
+	}
+}
+
+func WithTracerHits(hits bool) UserTracerOpt {
+	return func(opts *UserTracer) {
+		opts.hits = hits
+	}
+}
+
 func WithTracerVerbose(verbose bool) UserTracerOpt {
 	return func(opts *UserTracer) {
 		opts.verbose = verbose
@@ -49,8 +106,40 @@ func WithTracerWriter(w io.Writer) UserTracerOpt {
 	}
 }
 
+func WithTracerPid(pid int) UserTracerOpt {
+	return func(opts *UserTracer) {
+		opts.pid = pid
+	}
+}
+
+func WithTracerLaunch(args ...string) UserTracerOpt {
+	return func(opts *UserTracer) {
+		opts.launch = true
+		opts.args = args
+	}
+}
+
+func WithTracerCgroup(cgroup string) UserTracerOpt {
+	return func(opts *UserTracer) {
+		opts.cgroup = cgroup
+	}
+}
+
+func WithTracerFollowLibs(pattern string) UserTracerOpt {
+	return func(opts *UserTracer) {
+		opts.followLibsPattern = pattern
+	}
+}
+
+func WithTracerFollowLibsInterval(interval time.Duration) UserTracerOpt {
+	return func(opts *UserTracer) {
+		opts.followLibsInterval = interval
+	}
+}
+
+// WithTracerTracee adds the tracee to trace, along with the other ones.
 func WithTracerTracee(tracee *UserTracee) UserTracerOpt {
 	return func(opts *UserTracer) {
-		opts.tracee = tracee
+		opts.tracees = append(opts.tracees, tracee)
 	}
 }
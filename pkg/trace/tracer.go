@@ -6,16 +6,23 @@ import (
 	"encoding/binary"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/pkg/errors"
 
 	"github.com/maxgio92/xcover/internal/settings"
 	"github.com/maxgio92/xcover/internal/utils"
+	"github.com/maxgio92/xcover/pkg/cgroup"
 	"github.com/maxgio92/xcover/pkg/coverage"
 	"github.com/maxgio92/xcover/pkg/healthcheck"
 	"github.com/maxgio92/xcover/pkg/probe"
+	"github.com/maxgio92/xcover/pkg/static"
 )
 
 const (
@@ -23,11 +30,12 @@ const (
 	bpfMaxBufferSize               = 1024                 // Maximum size of bpf_attr needed to batch offsets for uprobe_multi attachments.
 	bpfUprobeMultiAttachMaxOffsets = bpfMaxBufferSize / 8 // 8 is the byte size of uint64 used to represent offsets.
 	HealthCheckSockPath            = "/tmp/xcover.sock"
+	DefaultFollowLibsInterval      = 1 * time.Second
 )
 
 var (
 	feedChBufSize  = 4096
-	ReportFileName = fmt.Sprintf("%s-report.json", settings.CmdName)
+	ReportFileName = ReportFilePath(coverage.ReportFormatJSON)
 )
 
 type FuncName struct {
@@ -36,26 +44,58 @@ type FuncName struct {
 
 type Event struct {
 	Cookie cookie
+	Label  labelID
+	// Gen is the reset generation the function was reported in.
+	Gen uint32
 }
 
 type UserTracer struct {
 	// Tracer objects.
 	probe *probe.Probe
-	// Tracee objects.
-	tracee *UserTracee
+	// Tracee objects, that are the executables traced, whose cookies
+	// are namespaced by their index.
+	tracees []*UserTracee
 	// User functions being acknowledged.
 	ack sync.Map
-	// User functions being consumed.
+	// User functions being acknowledged for each label.
+	ackByLabel sync.Map
+	// Labels marked, by ID starting from 1.
+	labels   []string
+	labelIDs map[string]labelID
+	labelsMu sync.Mutex
+	// Lock of the acknowledged functions while resetting the coverage.
+	resetMu sync.RWMutex
+	// Reset generation, incremented at each coverage reset, to discard
+	// the events reported before.
+	generation uint32
+	// User functions being consumed, reset at each status refresh.
 	consumed uint64
+	// User functions consumed in total.
+	consumedTotal uint64
+	// User functions attached and failed to attach.
+	funcsAttached uint64
+	funcsFailed   uint64
+	// Time tracing started.
+	startTime time.Time
 	// HealthCheck server.
 	hcServer *healthcheck.HealthCheckServer
+	// Stops tracing, set while running.
+	cancel context.CancelFunc
+	// Shared libraries to follow at runtime.
+	followLibsRegexp *regexp.Regexp
 
 	*UserTracerOptions
 }
 
 func NewUserTracer(opts ...UserTracerOpt) *UserTracer {
 	tracer := &UserTracer{
-		UserTracerOptions: &UserTracerOptions{},
+		labelIDs: make(map[string]labelID),
+		UserTracerOptions: &UserTracerOptions{
+			pid:                -1,
+			reportFormats:      []coverage.ReportFormat{coverage.ReportFormatJSON},
+			followLibsInterval: DefaultFollowLibsInterval,
+			socketPath:         HealthCheckSockPath,
+		},
 	}
 	for _, opt := range opts {
 		opt(tracer)
@@ -64,20 +104,45 @@ func NewUserTracer(opts ...UserTracerOpt) *UserTracer {
 	return tracer
 }
 
-func (t *UserTracer) validateTracee() error {
-	if t.tracee == nil {
+func (t *UserTracer) validateTracees() error {
+	if len(t.tracees) == 0 {
 		return ErrTraceeNil
 	}
-	if t.tracee.exePath == "" {
-		return ErrTraceeExePathEmpty
-	}
-	if len(t.tracee.funcs) == 0 {
-		return ErrTraceeFuncListEmpty
+	for _, tracee := range t.tracees {
+		if tracee == nil {
+			return ErrTraceeNil
+		}
+		if tracee.exePath == "" {
+			return ErrTraceeExePathEmpty
+		}
+		if len(tracee.funcs) == 0 {
+			return errors.Wrap(ErrTraceeFuncListEmpty, tracee.exePath)
+		}
 	}
 
 	return nil
 }
 
+// traceeOf returns the tracee of the function cookie, if any.
+func (t *UserTracer) traceeOf(c cookie) *UserTracee {
+	i := uint64(c >> traceeCookieShift)
+	if i >= uint64(len(t.tracees)) {
+		return nil
+	}
+
+	return t.tracees[i]
+}
+
+// funcsLen returns the number of functions traced of all the tracees.
+func (t *UserTracer) funcsLen() int {
+	var n int
+	for _, tracee := range t.tracees {
+		n += tracee.funcsLen()
+	}
+
+	return n
+}
+
 func (t *UserTracer) Init(ctx context.Context) error {
 	if t.writer == nil {
 		t.writer = os.Stdout
@@ -87,25 +152,61 @@ func (t *UserTracer) Init(ctx context.Context) error {
 
 	t.logger.Info().Msg("initializing tracer")
 
+	if t.launch && t.pid > 0 {
+		return ErrPidWithLaunch
+	}
+	if t.pid > 0 && len(t.tracees) > 1 {
+		return ErrPidWithTracees
+	}
+
+	if t.followLibsPattern != "" {
+		var err error
+		t.followLibsRegexp, err = regexp.Compile(t.followLibsPattern)
+		if err != nil {
+			return errors.Wrap(err, "invalid pattern of the shared libraries to follow")
+		}
+	}
+
+	var cgroupPath string
+	if t.cgroup != "" {
+		var err error
+		cgroupPath, err = t.resolveCgroup()
+		if err != nil {
+			return err
+		}
+	}
+
 	// Start the listener before initializing the BPF module
 	// and the tracee, because we want to notify the tracer
 	// is alive as soon as possible.
-	t.hcServer = healthcheck.NewHealthCheckServer(HealthCheckSockPath, t.logger)
+	t.hcServer = healthcheck.NewHealthCheckServer(t.socketPath, t.logger, healthcheck.WithHandler(t))
 	if err := t.hcServer.InitializeListener(ctx); err != nil {
 		return err
 	}
 
-	t.probe = probe.NewProbe(probe.WithLogger(t.logger))
+	t.probe = probe.NewProbe(
+		probe.WithLogger(t.logger),
+		probe.WithPid(t.pid),
+		probe.WithHits(t.hits),
+		probe.WithProcessTree(t.launch),
+		probe.WithCgroup(cgroupPath),
+	)
 	if err := t.probe.Init(ctx); err != nil {
 		return errors.Wrap(err, "error initializing BPF probe")
 	}
 
-	// Initialize the tracee includes to load all the data about
-	// the tracee, like symbols and function offsets.
-	if err := t.tracee.Init(); err != nil {
-		return errors.Wrapf(err, "failed to init tracer")
+	// Initialize the tracees includes to load all the data about
+	// the tracees, like symbols and function offsets.
+	for i, tracee := range t.tracees {
+		if tracee == nil {
+			return ErrTraceeNil
+		}
+		tracee.setCookieNamespace(i)
+		if err := tracee.Init(); err != nil {
+			return errors.Wrapf(err, "failed to init tracer")
+		}
 	}
-	if err := t.validateTracee(); err != nil {
+	if err := t.validateTracees(); err != nil {
 		return err
 	}
 
@@ -113,6 +214,9 @@ func (t *UserTracer) Init(ctx context.Context) error {
 }
 
 func (t *UserTracer) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	// Attach one uprobe per function to trace.
 	t.logger.Debug().Msg("attaching trace to selected functions")
 	t.attachProbe(ctx)
@@ -146,9 +250,36 @@ func (t *UserTracer) Run(ctx context.Context) error {
 		t.processEvents(ctx, feedCh)
 	}()
 
+	// Trace the shared libraries loaded at runtime.
+	if t.followLibsRegexp != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t.followLibs(ctx)
+		}()
+	}
+
+	// Start the program, and stop tracing when it exits.
+	var cmdErrCh chan error
+	if t.launch {
+		cmd, err := t.startProgram(ctx)
+		if err != nil {
+			cancel()
+			wg.Wait()
+			return err
+		}
+		cmdErrCh = make(chan error, 1)
+		go func() {
+			cmdErrCh <- cmd.Wait()
+			cancel()
+		}()
+	}
+
 	// Signal via the UDS that the tracer is ready,
 	// that is, it's consuming function events.
 	t.logger.Info().Msg("tracing functions")
+	t.cancel = cancel
+	t.startTime = time.Now()
 	t.hcServer.NotifyReadiness()
 
 	// Print status bar.
@@ -162,20 +293,72 @@ func (t *UserTracer) Run(ctx context.Context) error {
 	wg.Wait()
 	t.logger.Info().Msg("terminating...")
 
+	var cmdErr error
+	if cmdErrCh != nil {
+		if cmdErr = <-cmdErrCh; cmdErr != nil {
+			t.logger.Info().Err(cmdErr).Msg("the program failed")
+		}
+	}
+
 	// Stop listener.
 	if err := t.hcServer.ShutdownListener(); err != nil {
 		return errors.Wrap(err, "failed to stop listener")
 	}
 
+	report := t.buildReport()
+
 	// Write report.
-	return t.writeReport(ReportFileName)
+	if err := t.writeReport(report); err != nil {
+		return err
+	}
+
+	// The program failure takes precedence over the thresholds.
+	if cmdErr != nil {
+		return errors.Wrapf(cmdErr, "failed to run %s", t.tracees[0].exePath)
+	}
+
+	// Check the coverage against the thresholds.
+	return report.CheckThresholds(t.thresholds...)
+}
+
+// resolveCgroup returns the path of the cgroup v2 to filter.
+func (t *UserTracer) resolveCgroup() (string, error) {
+	path, err := cgroup.Resolve(cgroup.Root, t.cgroup)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid cgroup")
+	}
+	ok, err := cgroup.IsCgroup2(path)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", errors.Wrapf(cgroup.ErrNotCgroup2, "%s", path)
+	}
+	t.logger.Info().Str("cgroup", path).Msg("filtering cgroup")
+
+	return path, nil
+}
+
+// Ready returns a channel that is closed when the tracer is ready, that is
+// consuming the function events. It must be called after Init.
+func (t *UserTracer) Ready() <-chan struct{} {
+	return t.hcServer.Ready()
 }
 
 func (t *UserTracer) attachProbe(ctx context.Context) {
+	for _, tracee := range t.tracees {
+		for _, obj := range tracee.objects {
+			t.attachObjectProbe(ctx, tracee, obj.path)
+		}
+	}
+}
+
+// attachObjectProbe attaches the uprobes to the functions of the ELF object
+// of the tracee, in batches of offsets.
+func (t *UserTracer) attachObjectProbe(ctx context.Context, tracee *UserTracee, path string) {
 	batchSize := bpfUprobeMultiAttachMaxOffsets
 
-	offsets := t.tracee.GetFuncOffsets()
-	cookies := t.tracee.GetFuncCookies()
+	offsets, cookies := tracee.getObjectFuncs(path)
 
 	for i := 0; i < len(offsets); i += batchSize {
 		end := i + batchSize
@@ -183,9 +366,12 @@ func (t *UserTracer) attachProbe(ctx context.Context) {
 			end = len(offsets)
 		}
 
-		if err := t.probe.Attach(ctx, t.tracee.exePath, offsets[i:end], cookies[i:end]); err != nil {
-			t.logger.Warn().Err(errors.Wrapf(err, "error attaching uprobe for functions with cookies: %v", cookies[i:end]))
+		if err := t.probe.Attach(ctx, path, offsets[i:end], cookies[i:end]); err != nil {
+			t.logger.Warn().Err(err).Str("path", path).Msg("failed to attach functions")
+			atomic.AddUint64(&t.funcsFailed, uint64(end-i))
+			continue
 		}
+		atomic.AddUint64(&t.funcsAttached, uint64(end-i))
 	}
 }
 
@@ -215,6 +401,7 @@ func (t *UserTracer) processEvents(ctx context.Context, feed <-chan []byte) {
 // TODO: decouple handle from handler functions as argument.
 func (t *UserTracer) handleEvent(data []byte) {
 	atomic.AddUint64(&t.consumed, 1)
+	atomic.AddUint64(&t.consumedTotal, 1)
 
 	var event Event
 
@@ -223,58 +410,235 @@ func (t *UserTracer) handleEvent(data []byte) {
 		t.logger.Err(err).Msg("failed to read event")
 	}
 
-	if t.tracee == nil {
+	if len(t.tracees) == 0 {
 		return
 	}
-	fun, ok := t.tracee.funcs[event.Cookie]
+	var (
+		fun funcInfo
+		ok  bool
+	)
+	if tracee := t.traceeOf(event.Cookie); tracee != nil {
+		fun, ok = tracee.getFunc(event.Cookie)
+	}
 	if !ok {
 		t.logger.Err(ErrFuncNotFoundForCookie).Msg("failed getting function from cookie")
 	}
 
+	// Do not acknowledge functions while resetting the coverage.
+	t.resetMu.RLock()
+	defer t.resetMu.RUnlock()
+
+	// Do not acknowledge functions reported before the last reset, still
+	// queued when resetting.
+	if event.Gen != t.generation {
+		return
+	}
+
 	if _, ok := t.ack.Load(event.Cookie); !ok {
 		if t.verbose && t.writer != nil {
-			fmt.Fprintln(t.writer, fun.name)
+			fmt.Fprintln(t.writer, fun.displayName())
 		}
 		t.ack.Store(event.Cookie, struct{}{})
 	}
+	if event.Label != 0 {
+		t.ackByLabel.Store(labeledCookie{label: event.Label, cookie: event.Cookie}, struct{}{})
+	}
 }
 
-func (t *UserTracer) writeReport(reportPath string) error {
+// getHits returns the hits counted by the probe for each function cookie.
+// It returns nil when hit counting is disabled.
+func (t *UserTracer) getHits() map[uint64]uint64 {
+	if !t.hits || t.probe == nil {
+		return nil
+	}
+
+	hits, err := t.probe.GetHits()
+	if err != nil {
+		t.logger.Warn().Err(err).Msg("failed to get function hits")
+		return nil
+	}
+
+	return hits
+}
+
+// getFuncsHits returns the hits for each function name of the tracee,
+// from the hits counted for each function cookie.
+func getFuncsHits(tracee *UserTracee, hits map[uint64]uint64) map[string]uint64 {
+	if hits == nil {
+		return nil
+	}
+
+	funcsHits := make(map[string]uint64, len(hits))
+	for c, n := range hits {
+		fun, ok := tracee.funcs[cookie(c)]
+		if !ok {
+			continue
+		}
+		funcsHits[fun.displayName()] += n
+	}
+
+	return funcsHits
+}
+
+func (t *UserTracer) writeReport(report *coverage.CoverageReport) error {
 	if !t.report {
 		return nil
 	}
 
-	traced := make([]string, 0, len(t.tracee.funcs))
-	for _, fn := range t.tracee.funcs {
-		traced = append(traced, fn.name)
+	for _, format := range t.reportFormats {
+		if err := t.writeReportFile(report, format, t.reportFilePath(format)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// buildReport returns the report of the tracee, or the aggregate of the
+// reports of the tracees, when more.
+func (t *UserTracer) buildReport() *coverage.CoverageReport {
+	hits := t.getHits()
+
+	if len(t.tracees) == 1 {
+		return t.buildTraceeReport(t.tracees[0], hits)
+	}
+
+	reports := make([]*coverage.CoverageReport, 0, len(t.tracees))
+	for _, tracee := range t.tracees {
+		reports = append(reports, t.buildTraceeReport(tracee, hits))
+	}
+
+	return coverage.Aggregate(reports...)
+}
+
+func (t *UserTracer) buildTraceeReport(tracee *UserTracee, hits map[uint64]uint64) *coverage.CoverageReport {
+	traced := make([]string, 0, len(tracee.funcs))
+	source := make(map[string]coverage.FuncSource)
+	aliases := make(map[string][]string)
+	for _, fn := range tracee.funcs {
+		traced = append(traced, fn.displayName())
+		if len(fn.aliases) > 0 {
+			aliases[fn.displayName()] = fn.aliases
+		}
+		if fn.file != "" {
+			source[fn.displayName()] = coverage.FuncSource{
+				File:     fn.file,
+				Line:     fn.line,
+				CompUnit: fn.compUnit,
+			}
+		}
 	}
 
 	ack := make([]string, 0, utils.LenSyncMap(&t.ack))
 	t.ack.Range(func(k, v interface{}) bool {
-		fun, ok := t.tracee.funcs[k.(cookie)]
+		// The functions of the other tracees are not found.
+		fun, ok := tracee.funcs[k.(cookie)]
 		if !ok {
-			return false
+			return true
 		}
-		ack = append(ack, fun.name)
+		ack = append(ack, fun.displayName())
 		return true
 	})
 
-	covByFunc := float64(utils.LenSyncMap(&t.ack)) / float64(len(t.tracee.funcs)) * 100
+	covByFunc := float64(len(ack)) / float64(len(tracee.funcs)) * 100
+
+	buildID := t.getBuildID(tracee.exePath)
 
-	report := coverage.NewCoverageReport(
+	return coverage.NewCoverageReport(
 		coverage.WithReportFuncsAck(ack),
 		coverage.WithReportFuncsTraced(traced),
 		coverage.WithReportFuncsCov(covByFunc),
-		coverage.WithReportExePath(t.tracee.exePath),
+		coverage.WithReportFuncsHits(getFuncsHits(tracee, hits)),
+		coverage.WithReportFuncsSource(source),
+		coverage.WithReportFuncsAliases(aliases),
+		coverage.WithReportFuncsAckByLabel(t.getFuncsAckByLabel(tracee)),
+		coverage.WithReportExePath(tracee.exePath),
+		coverage.WithReportBuildID(buildID),
+		coverage.WithReportObjects(t.buildObjectsCoverage(tracee)),
 	)
+}
+
+// buildObjectsCoverage returns the coverage of each ELF object traced of
+// the tracee. It returns nil when only the executable is traced.
+func (t *UserTracer) buildObjectsCoverage(tracee *UserTracee) []coverage.ObjectCoverage {
+	if len(tracee.objects) < 2 {
+		return nil
+	}
+
+	objects := make([]coverage.ObjectCoverage, 0, len(tracee.objects))
+	index := make(map[string]int, len(tracee.objects))
+	for _, obj := range tracee.objects {
+		index[obj.path] = len(objects)
+		objects = append(objects, coverage.ObjectCoverage{
+			Path:        obj.path,
+			FuncsTraced: []string{},
+			FuncsAck:    []string{},
+			BuildID:     t.getBuildID(obj.path),
+		})
+	}
+
+	for c, fn := range tracee.funcs {
+		i, ok := index[fn.object]
+		if !ok {
+			continue
+		}
+		objects[i].FuncsTraced = append(objects[i].FuncsTraced, fn.displayName())
+		if _, ok := t.ack.Load(c); ok {
+			objects[i].FuncsAck = append(objects[i].FuncsAck, fn.displayName())
+		}
+	}
+
+	for i := range objects {
+		sort.Strings(objects[i].FuncsTraced)
+		sort.Strings(objects[i].FuncsAck)
+		if len(objects[i].FuncsTraced) > 0 {
+			objects[i].CovByFunc = float64(len(objects[i].FuncsAck)) / float64(len(objects[i].FuncsTraced)) * 100
+		}
+	}
+
+	return objects
+}
 
+func (t *UserTracer) getBuildID(path string) string {
+	buildID, err := static.GetBuildID(path)
+	if err != nil {
+		t.logger.Debug().Err(err).Str("path", path).Msg("failed to get build ID")
+	}
+
+	return buildID
+}
+
+func (t *UserTracer) writeReportFile(report *coverage.CoverageReport, format coverage.ReportFormat, reportPath string) error {
 	file, err := os.Create(reportPath)
 	if err != nil {
-		t.logger.Err(err).Msg("failed to create report file")
+		return errors.Wrap(err, "failed to create report file")
 	}
 	defer file.Close()
 
-	t.logger.Info().Str("path", reportPath).Msgf("report generated")
+	if err := report.WriteReportFormat(file, format); err != nil {
+		return errors.Wrapf(err, "failed to write %s report", format)
+	}
+	t.logger.Info().Str("path", reportPath).Str("format", string(format)).Msgf("report generated")
+
+	return nil
+}
+
+// reportFilePath returns the path of the report file for the format, from
+// the report path when set, or the default one.
+func (t *UserTracer) reportFilePath(format coverage.ReportFormat) string {
+	if t.reportPath == "" {
+		return ReportFilePath(format)
+	}
+
+	return ReportPathWithFormat(t.reportPath, format)
+}
+
+// ReportPathWithFormat returns the report path with the extension of the format.
+func ReportPathWithFormat(path string, format coverage.ReportFormat) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + "." + format.FileExt()
+}
 
-	return report.WriteReport(file)
+// ReportFilePath returns the path of the report file for the format.
+func ReportFilePath(format coverage.ReportFormat) string {
+	return fmt.Sprintf("%s-report.%s", settings.CmdName, format.FileExt())
 }
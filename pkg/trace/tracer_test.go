@@ -2,10 +2,20 @@ package trace
 
 import (
 	"bytes"
+	"context"
+	"debug/elf"
 	"encoding/binary"
 	"github.com/stretchr/testify/require"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
 	"testing"
+	"time"
+
+	"github.com/maxgio92/xcover/pkg/cgroup"
+	"github.com/maxgio92/xcover/pkg/coverage"
+	"github.com/maxgio92/xcover/pkg/static"
 )
 
 const (
@@ -23,21 +33,11 @@ func TestHandleEvent_Verbose(t *testing.T) {
 	err := tracee.Init()
 	require.NoError(t, err)
 
-	probe, err := os.ReadFile("testdata/test.bpf.o")
-	require.NoError(t, err)
-
 	tracer := NewUserTracer(
-		WithTracerBpfObjBuf(probe),
-		WithTracerBpfObjName("test.bpf.o"),
 		WithTracerVerbose(true),
 		WithTracerWriter(&buf),
 		WithTracerTracee(tracee),
 	)
-	// TODO: need permission to set rlimit while creating the libbpf BPF module.
-	//err = tracer.Init()
-	//require.NoError(t, err)
-	//err = tracer.Load()
-	//require.NoError(t, err)
 
 	// Encode the Event
 	event := Event{Cookie: 1}
@@ -52,3 +52,410 @@ func TestHandleEvent_Verbose(t *testing.T) {
 	_, ok := tracer.ack.Load(cookie(1))
 	require.True(t, ok)
 }
+
+func TestHandleEvent_Label(t *testing.T) {
+	tracee := NewUserTracee(WithTraceeExePath("mybin"))
+	tracee.funcs = map[cookie]funcInfo{1: {name: "foo"}, 2: {name: "bar"}}
+
+	tracer := NewUserTracer(WithTracerTracee(tracee))
+	tracer.labels = []string{"test1", "test2"}
+	tracer.labelIDs = map[string]labelID{"test1": 1, "test2": 2}
+
+	for _, event := range []Event{{Cookie: 1, Label: 1}, {Cookie: 2, Label: 1}, {Cookie: 1, Label: 2}, {Cookie: 2}} {
+		data := new(bytes.Buffer)
+		require.NoError(t, binary.Write(data, binary.LittleEndian, event))
+		tracer.handleEvent(data.Bytes())
+	}
+
+	report := tracer.buildReport()
+	require.ElementsMatch(t, []string{"foo", "bar"}, report.FuncsAck)
+	require.Equal(t, map[string][]string{
+		"test1": {"bar", "foo"},
+		"test2": {"foo"},
+	}, report.FuncsAckByLabel)
+}
+
+func TestHandleEvent_Reset(t *testing.T) {
+	tracee := NewUserTracee(WithTraceeExePath("mybin"))
+	tracee.funcs = map[cookie]funcInfo{1: {name: "foo"}, 2: {name: "bar"}}
+
+	tracer := NewUserTracer(WithTracerTracee(tracee))
+	handle := func(event Event) {
+		data := new(bytes.Buffer)
+		require.NoError(t, binary.Write(data, binary.LittleEndian, event))
+		tracer.handleEvent(data.Bytes())
+	}
+
+	handle(Event{Cookie: 1})
+	require.Equal(t, []string{"foo"}, tracer.buildReport().FuncsAck)
+
+	require.NoError(t, tracer.Reset("", ""))
+	require.Empty(t, tracer.buildReport().FuncsAck)
+
+	// The event reported before the reset and still queued is not counted.
+	handle(Event{Cookie: 2})
+	require.Empty(t, tracer.buildReport().FuncsAck)
+
+	handle(Event{Cookie: 2, Gen: 1})
+	require.Equal(t, []string{"bar"}, tracer.buildReport().FuncsAck)
+}
+
+func TestNewUserTracer_Pid(t *testing.T) {
+	tracer := NewUserTracer()
+	require.Equal(t, -1, tracer.pid)
+
+	tracer = NewUserTracer(WithTracerPid(1234))
+	require.Equal(t, 1234, tracer.pid)
+}
+
+func TestNewUserTracer_Launch(t *testing.T) {
+	tracer := NewUserTracer()
+	require.False(t, tracer.launch)
+
+	tracer = NewUserTracer(WithTracerLaunch("-v", "foo"))
+	require.True(t, tracer.launch)
+	require.Equal(t, []string{"-v", "foo"}, tracer.args)
+
+	tracer = NewUserTracer(WithTracerLaunch(), WithTracerPid(1234))
+	require.ErrorIs(t, tracer.Init(context.Background()), ErrPidWithLaunch)
+}
+
+func TestUserTracer_Init_PidWithTracees(t *testing.T) {
+	tracer := NewUserTracer(
+		WithTracerTracee(NewUserTracee(WithTraceeExePath("api"))),
+		WithTracerTracee(NewUserTracee(WithTraceeExePath("worker"))),
+		WithTracerPid(1234),
+	)
+	require.ErrorIs(t, tracer.Init(context.Background()), ErrPidWithTracees)
+}
+
+func TestUserTracer_Init_Cgroup(t *testing.T) {
+	tracer := NewUserTracer(WithTracerCgroup("/nonexistent.scope"))
+	require.ErrorIs(t, tracer.Init(context.Background()), cgroup.ErrCgroupNotFound)
+}
+
+func TestUserTracer_GetHits_Disabled(t *testing.T) {
+	tracer := NewUserTracer()
+	require.False(t, tracer.hits)
+	require.Nil(t, tracer.getHits())
+}
+
+func TestUserTracer_BuildTraceeReport_FuncsHits(t *testing.T) {
+	tracee := NewUserTracee(WithTraceeExePath("mybin"))
+	tracee.funcs = map[cookie]funcInfo{
+		1: {name: "foo"},
+		2: {name: "bar"},
+	}
+
+	tracer := NewUserTracer(WithTracerTracee(tracee))
+	tracer.ack.Store(cookie(1), struct{}{})
+
+	// Hits of functions of other tracees are not reported.
+	report := tracer.buildTraceeReport(tracee, map[uint64]uint64{1: 3, 3: 5})
+	require.Equal(t, map[string]uint64{"foo": 3}, report.FuncsHits)
+	require.Equal(t, []string{"foo"}, report.FuncsAck)
+
+	report = tracer.buildTraceeReport(tracee, nil)
+	require.Nil(t, report.FuncsHits)
+}
+
+func TestUserTracer_BuildReport_FuncsSource(t *testing.T) {
+	tracee := NewUserTracee(
+		WithTraceeExePath("testdata/gotest"),
+		WithTraceeSymPatternInclude("^main\\."),
+	)
+	err := tracee.Init()
+	require.NoError(t, err)
+
+	tracer := NewUserTracer(WithTracerTracee(tracee))
+	for c, fn := range tracee.funcs {
+		if fn.name == "main.fooFunction" {
+			tracer.ack.Store(c, struct{}{})
+		}
+	}
+
+	report := tracer.buildReport()
+	require.Equal(t, []string{"main.fooFunction"}, report.FuncsAck)
+	require.NotEmpty(t, report.BuildID)
+
+	source, ok := report.FuncsSource["main.fooFunction"]
+	require.True(t, ok)
+	require.Equal(t, "test.go", filepath.Base(source.File))
+	require.Equal(t, 9, source.Line)
+	require.Equal(t, "main", source.CompUnit)
+}
+
+func TestUserTracer_BuildReport_Objects(t *testing.T) {
+	tracee := NewUserTracee(WithTraceeExePath("mybin"))
+	exe := &elfObject{path: "mybin"}
+	lib := &elfObject{path: "libfoo.so", lib: true}
+	tracee.objects = []*elfObject{exe, lib}
+	tracee.funcs = map[cookie]funcInfo{
+		1: {name: "foo", object: exe.path},
+		2: {name: "bar", object: exe.path},
+		3: {name: "foo", object: lib.path},
+	}
+
+	tracer := NewUserTracer(WithTracerTracee(tracee))
+	tracer.ack.Store(cookie(3), struct{}{})
+
+	report := tracer.buildReport()
+	require.Len(t, report.Objects, 2)
+	require.Equal(t, "mybin", report.Objects[0].Path)
+	require.Equal(t, []string{"bar", "foo"}, report.Objects[0].FuncsTraced)
+	require.Empty(t, report.Objects[0].FuncsAck)
+	require.Equal(t, "libfoo.so", report.Objects[1].Path)
+	require.Equal(t, []string{"foo"}, report.Objects[1].FuncsAck)
+	require.Equal(t, 100.0, report.Objects[1].CovByFunc)
+
+	// Only the executable traced.
+	tracee.objects = tracee.objects[:1]
+	require.Nil(t, tracer.buildReport().Objects)
+}
+
+func TestUserTracer_ScanLibs(t *testing.T) {
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep not found")
+	}
+	if needed, err := static.GetNeededLibs(sleep); err != nil || len(needed) == 0 {
+		t.Skip("sleep is not dynamically linked")
+	}
+	cmd := exec.Command(sleep, "10")
+	require.NoError(t, cmd.Start())
+	defer cmd.Process.Kill()
+
+	tracee := NewUserTracee(
+		WithTraceeExePath("testdata/gotest"),
+		WithTraceeSymPatternInclude("^main\\.fooFunction$|^malloc$"),
+	)
+	require.NoError(t, tracee.Init())
+
+	tracer := NewUserTracer(
+		WithTracerTracee(tracee),
+		WithTracerPid(cmd.Process.Pid),
+		WithTracerFollowLibs("/libc\\.so"),
+	)
+	tracer.followLibsRegexp = regexp.MustCompile(tracer.followLibsPattern)
+
+	// Wait for the dynamic linker to map the libraries.
+	var libs []string
+	seen := make(map[string]struct{})
+	require.Eventually(t, func() bool {
+		libs = append(libs, tracer.scanLibs(tracee, seen)...)
+		return len(libs) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Len(t, libs, 1)
+	require.True(t, tracee.hasObject(libs[0]))
+	require.ElementsMatch(t, []string{"main.fooFunction", "malloc"}, tracee.GetFuncNames())
+
+	// Libraries already looked at.
+	require.Empty(t, tracer.scanLibs(tracee, seen))
+}
+
+func TestUserTracer_ScanLibs_Symlink(t *testing.T) {
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep not found")
+	}
+	needed, err := static.GetNeededLibs(sleep)
+	if err != nil {
+		t.Skip("sleep is not dynamically linked")
+	}
+	var libc string
+	for _, lib := range needed {
+		if filepath.Base(lib) == "libc.so.6" {
+			libc = lib
+		}
+	}
+	if libc == "" {
+		t.Skip("libc not found")
+	}
+	link := filepath.Join(t.TempDir(), "libc.so")
+	require.NoError(t, os.Symlink(libc, link))
+
+	cmd := exec.Command(sleep, "10")
+	require.NoError(t, cmd.Start())
+	defer cmd.Process.Kill()
+
+	tracee := NewUserTracee(
+		WithTraceeExePath("testdata/gotest"),
+		WithTraceeLibPaths(link),
+		WithTraceeSymPatternInclude("^main\\.fooFunction$|^malloc$"),
+	)
+	require.NoError(t, tracee.Init())
+	require.True(t, tracee.hasObject(libc))
+
+	tracer := NewUserTracer(
+		WithTracerTracee(tracee),
+		WithTracerPid(cmd.Process.Pid),
+		WithTracerFollowLibs("/libc[.-]"),
+	)
+	tracer.followLibsRegexp = regexp.MustCompile(tracer.followLibsPattern)
+
+	// The library specified through the link is not traced again.
+	seen := make(map[string]struct{})
+	require.Never(t, func() bool {
+		return len(tracer.scanLibs(tracee, seen)) > 0
+	}, 500*time.Millisecond, 10*time.Millisecond)
+	require.Len(t, tracee.GetFuncNames(), 2)
+}
+
+func TestUserTracee_DisambiguateFuncs(t *testing.T) {
+	tracee := NewUserTracee()
+	tracee.funcs = map[cookie]funcInfo{
+		1: {name: "foo", offset: 0x10, file: "a.c", line: 1},
+		2: {name: "foo", offset: 0x20, file: "b.c", line: 1},
+		3: {name: "bar", offset: 0x30, file: "a.h", line: 2},
+		4: {name: "bar", offset: 0x40, file: "a.h", line: 2},
+		5: {name: "baz", offset: 0x50, object: "mybin"},
+		6: {name: "baz", offset: 0x50, object: "libfoo.so"},
+		7: {name: "qux", offset: 0x60},
+	}
+	tracee.disambiguateFuncs([]cookie{1, 2, 3, 4, 5, 6, 7})
+
+	require.Equal(t, "foo (a.c:1)", tracee.funcs[1].displayName())
+	require.Equal(t, "foo (b.c:1)", tracee.funcs[2].displayName())
+	require.Equal(t, "bar (0x30)", tracee.funcs[3].displayName())
+	require.Equal(t, "bar (0x40)", tracee.funcs[4].displayName())
+	require.Equal(t, "baz (mybin:0x50)", tracee.funcs[5].displayName())
+	require.Equal(t, "baz (libfoo.so:0x50)", tracee.funcs[6].displayName())
+	require.Equal(t, "qux", tracee.funcs[7].displayName())
+}
+
+func TestUserTracee_DisambiguateFuncs_Added(t *testing.T) {
+	tracee := NewUserTracee()
+	tracee.funcs = map[cookie]funcInfo{
+		1: {name: "foo", offset: 0x10, object: "mybin", file: "a.c", line: 1},
+		2: {name: "bar", offset: 0x20, object: "mybin", file: "a.c", line: 2},
+		3: {name: "bar", offset: 0x30, object: "mybin", file: "b.c", line: 2},
+	}
+	tracee.disambiguateFuncs([]cookie{1, 2, 3})
+
+	// Functions of a shared library added at runtime.
+	tracee.funcs[4] = funcInfo{name: "foo", offset: 0x10, object: "libfoo.so", file: "c.c", line: 1}
+	tracee.funcs[5] = funcInfo{name: "bar", offset: 0x40, object: "libfoo.so", file: "a.c", line: 2}
+	tracee.funcs[6] = funcInfo{name: "baz", offset: 0x50, object: "libfoo.so"}
+	tracee.disambiguateFuncs([]cookie{4, 5, 6})
+
+	// The report names already handed out are kept.
+	require.Equal(t, "foo", tracee.funcs[1].displayName())
+	require.Equal(t, "bar (a.c:2)", tracee.funcs[2].displayName())
+	require.Equal(t, "bar (b.c:2)", tracee.funcs[3].displayName())
+
+	require.Equal(t, "foo (c.c:1)", tracee.funcs[4].displayName())
+	require.Equal(t, "bar (0x40)", tracee.funcs[5].displayName())
+	require.Equal(t, "baz", tracee.funcs[6].displayName())
+}
+
+func TestFuncInfo_WithAlias(t *testing.T) {
+	fn := funcInfo{name: "__libc_malloc", rawName: "__libc_malloc", bind: elf.STB_LOCAL}
+
+	fn = fn.withAlias("malloc", elf.Symbol{Name: "malloc", Info: elf.ST_INFO(elf.STB_GLOBAL, elf.STT_FUNC)}, static.SourceLocation{})
+	require.Equal(t, "malloc", fn.name)
+	require.Equal(t, []string{"__libc_malloc"}, fn.aliases)
+
+	fn = fn.withAlias("__malloc", elf.Symbol{Name: "__malloc", Info: elf.ST_INFO(elf.STB_WEAK, elf.STT_FUNC)}, static.SourceLocation{File: "malloc.c", Line: 3})
+	require.Equal(t, "malloc", fn.name)
+	require.Equal(t, []string{"__libc_malloc", "__malloc"}, fn.aliases)
+	require.Equal(t, "malloc.c", fn.file)
+}
+
+func TestUserTracer_Snapshot(t *testing.T) {
+	tracee := NewUserTracee(WithTraceeExePath("mybin"))
+	tracee.funcs = map[cookie]funcInfo{1: {name: "foo"}, 2: {name: "bar"}}
+
+	tracer := NewUserTracer(WithTracerTracee(tracee))
+	tracer.ack.Store(cookie(1), struct{}{})
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	require.NoError(t, tracer.Snapshot(coverage.ReportFormatJSON, path))
+
+	report, err := coverage.ReadReportFile(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"foo"}, report.FuncsAck)
+	require.Equal(t, 50.0, report.CovByFunc)
+
+	require.ErrorIs(t, tracer.Snapshot("unknown", path), coverage.ErrReportFormatUnknown)
+}
+
+func TestUserTracer_Status(t *testing.T) {
+	tracee := NewUserTracee(WithTraceeExePath("mybin"))
+	tracee.funcs = map[cookie]funcInfo{1: {name: "foo"}, 2: {name: "bar"}}
+
+	tracer := NewUserTracer(WithTracerTracee(tracee))
+	tracer.startTime = time.Now().Add(-time.Minute)
+	tracer.funcsAttached = 2
+
+	data := new(bytes.Buffer)
+	require.NoError(t, binary.Write(data, binary.LittleEndian, Event{Cookie: 1}))
+	tracer.handleEvent(data.Bytes())
+
+	status, err := tracer.Status()
+	require.NoError(t, err)
+	require.Equal(t, "mybin", status.ExePath)
+	require.Equal(t, 2, status.FuncsTraced)
+	require.Equal(t, uint64(2), status.FuncsAttached)
+	require.Equal(t, 1, status.FuncsAck)
+	require.Equal(t, 50.0, status.CovByFunc)
+	require.Equal(t, uint64(1), status.EventsConsumed)
+	require.GreaterOrEqual(t, status.Uptime, time.Minute)
+}
+
+func TestUserTracer_ReportFilePath(t *testing.T) {
+	tracer := NewUserTracer()
+	require.Equal(t, "xcover-report.html", tracer.reportFilePath(coverage.ReportFormatHTML))
+
+	tracer = NewUserTracer(WithTracerReportPath("/tmp/ci/cov.json"))
+	require.Equal(t, "/tmp/ci/cov.json", tracer.reportFilePath(coverage.ReportFormatJSON))
+	require.Equal(t, "/tmp/ci/cov.html", tracer.reportFilePath(coverage.ReportFormatHTML))
+}
+
+func TestUserTracer_BuildReport_Tracees(t *testing.T) {
+	api := NewUserTracee(WithTraceeExePath("/bin/api"))
+	api.funcs = map[cookie]funcInfo{1: {name: "main.main"}, 2: {name: "main.serve"}}
+	worker := NewUserTracee(WithTraceeExePath("/bin/worker"))
+	worker.funcs = map[cookie]funcInfo{1<<traceeCookieShift + 1: {name: "main.main"}}
+
+	tracer := NewUserTracer(WithTracerTracee(api), WithTracerTracee(worker))
+	require.Same(t, api, tracer.traceeOf(2))
+	require.Same(t, worker, tracer.traceeOf(1<<traceeCookieShift+1))
+	require.Nil(t, tracer.traceeOf(2<<traceeCookieShift+1))
+
+	for _, event := range []Event{{Cookie: 2}, {Cookie: 1<<traceeCookieShift + 1}} {
+		data := new(bytes.Buffer)
+		require.NoError(t, binary.Write(data, binary.LittleEndian, event))
+		tracer.handleEvent(data.Bytes())
+	}
+
+	report := tracer.buildReport()
+	require.Equal(t, []string{"api:main.main", "api:main.serve", "worker:main.main"}, report.FuncsTraced)
+	require.Equal(t, []string{"api:main.serve", "worker:main.main"}, report.FuncsAck)
+	require.Len(t, report.Executables, 2)
+	require.Equal(t, "/bin/api", report.Executables[0].ExePath)
+	require.Equal(t, []string{"main.serve"}, report.Executables[0].FuncsAck)
+	require.Equal(t, 50.0, report.Executables[0].CovByFunc)
+	require.Equal(t, "/bin/worker", report.Executables[1].ExePath)
+	require.Equal(t, 100.0, report.Executables[1].CovByFunc)
+
+	status, err := tracer.Status()
+	require.NoError(t, err)
+	require.Equal(t, "/bin/api", status.ExePath)
+	require.Equal(t, []string{"/bin/api", "/bin/worker"}, status.ExePaths)
+	require.Equal(t, 3, status.FuncsTraced)
+	require.Equal(t, 2, status.FuncsAck)
+}
+
+func TestUserTracee_SetCookieNamespace(t *testing.T) {
+	tracee := NewUserTracee(
+		WithTraceeExePath("testdata/gotest"),
+		WithTraceeSymPatternInclude("^main\\."),
+	)
+	tracee.setCookieNamespace(1)
+	require.NoError(t, tracee.Init())
+	require.NotEmpty(t, tracee.funcs)
+	for c := range tracee.funcs {
+		require.Equal(t, cookie(1), c>>traceeCookieShift)
+		require.NotZero(t, c&(1<<traceeCookieShift-1))
+	}
+}